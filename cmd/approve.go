@@ -0,0 +1,156 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kireledan/gojenkins"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+const (
+	inputProceed = "Proceed"
+	inputAbort   = "Abort"
+	inputLater   = "Decide later"
+)
+
+// approveCmd represents the approve command
+var approveCmd = &cobra.Command{
+	Use:   "approve [job] [build#]",
+	Short: "Answer the input steps a pipeline is waiting on",
+	Long: `approve shows the input steps a pipeline build is paused on ("Proceed to prod?") and asks
+	whether to proceed or abort, prompting for the step's parameters when it has any.
+
+	Pass --proceed or --abort to answer without prompts, using --set KEY=VALUE for the parameters.
+	run, rerun and latest take --approve to be asked as soon as a build they follow pauses.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, build := locateBuild(jenkins(cmd), args)
+		inputs, err := pkg.GetPendingInputs(context.TODO(), build)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(inputs) == 0 {
+			fmt.Print("Build ", Cyan(build.GetBuildNumber()), " is not waiting for input.\n")
+			return
+		}
+
+		proceed, _ := cmd.Flags().GetBool("proceed")
+		abort, _ := cmd.Flags().GetBool("abort")
+		sets, _ := cmd.Flags().GetStringArray("set")
+		for _, input := range inputs {
+			switch {
+			case proceed && abort:
+				log.Fatal("pass either --proceed or --abort")
+			case proceed:
+				values, err := inputValues(input, sets)
+				if err != nil {
+					log.Fatal(err)
+				}
+				err = pkg.ProceedInput(context.TODO(), build, input, values)
+			case abort:
+				err = pkg.AbortInput(context.TODO(), build, input)
+			default:
+				err = promptInput(context.TODO(), build, input)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+// promptInput asks how to answer an input step and submits the answer.
+func promptInput(ctx context.Context, B *gojenkins.Build, input pkg.InputRequest) error {
+	fmt.Print("\nBuild ", Cyan(B.GetBuildNumber()), " is waiting for input: ", Yellow(input.Message), "\n")
+	proceedText := input.ProceedText
+	if proceedText == "" {
+		proceedText = inputProceed
+	}
+	answer := ""
+	survey.AskOne(&survey.Select{
+		Message: "What now?",
+		Options: []string{proceedText, inputAbort, inputLater},
+	}, &answer)
+
+	switch answer {
+	case proceedText:
+		values := map[string]string{}
+		for _, param := range input.Parameters {
+			values[param.Name] = askParameter(param, pkg.ParameterDefault(param))
+		}
+		if err := pkg.ProceedInput(ctx, B, input, values); err != nil {
+			return err
+		}
+		fmt.Println(Green("Proceeding"))
+	case inputAbort:
+		if err := pkg.AbortInput(ctx, B, input); err != nil {
+			return err
+		}
+		fmt.Println(Red("Aborted"))
+	default:
+		fmt.Println("Left for later, answer it with 'goose approve' or in jenkins")
+	}
+	return nil
+}
+
+// inputValues answers the parameters of an input step with their defaults
+// and the --set KEY=VALUE flags.
+func inputValues(input pkg.InputRequest, sets []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, param := range input.Parameters {
+		values[param.Name] = pkg.ParameterDefault(param)
+	}
+	for _, set := range sets {
+		kv := strings.SplitN(set, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("--set %s is not KEY=VALUE", set)
+		}
+		param, ok := pkg.FindParameter(input.Parameters, kv[0])
+		if !ok {
+			return nil, fmt.Errorf("the input step has no parameter %s", kv[0])
+		}
+		value, err := pkg.CheckParameter(param, kv[1])
+		if err != nil {
+			return nil, err
+		}
+		values[kv[0]] = value
+	}
+	return values, nil
+}
+
+func init() {
+	rootCmd.AddCommand(approveCmd)
+
+	approveCmd.Flags().Bool("proceed", false, "proceed without prompting")
+	approveCmd.Flags().Bool("abort", false, "abort without prompting")
+	approveCmd.Flags().StringArray("set", nil, "answer an input parameter with KEY=VALUE (repeatable)")
+}
@@ -0,0 +1,141 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/kireledan/gojenkins"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// artifactsCmd represents the artifacts command
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts [job] [build#]",
+	Short: "List and download the artifacts of a build",
+	Long: `artifacts lists the files archived by a build with their sizes.
+
+	Use --download to save them, keeping their paths, under --dir. --glob limits both the list and the download to
+	artifacts whose path or file name matches, e.g. --glob '*.jar' --glob 'reports/*'.
+	--last-successful uses the last successful build of the job instead of its last build.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		lastSuccessful, _ := cmd.Flags().GetBool("last-successful")
+		download, _ := cmd.Flags().GetBool("download")
+		globs, _ := cmd.Flags().GetStringArray("glob")
+		dir, _ := cmd.Flags().GetString("dir")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+
+		var build *gojenkins.Build
+		if lastSuccessful {
+			if len(args) > 1 {
+				log.Fatal("--last-successful doesn't take a build number")
+			}
+			_, build = locateLastSuccessfulBuild(jenkins(cmd), args)
+		} else {
+			_, build = locateBuild(jenkins(cmd), args)
+		}
+		artifacts, err := pkg.ListArtifacts(context.TODO(), build, parallel)
+		if err != nil {
+			log.Fatal(err)
+		}
+		artifacts, err = pkg.FilterArtifacts(artifacts, globs)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if !download {
+			if structuredOutput() {
+				printStructured(artifacts)
+				return
+			}
+			fmt.Println(build.GetUrl())
+			printArtifacts(artifacts)
+			return
+		}
+
+		if len(artifacts) == 0 {
+			log.Fatal("no artifacts to download")
+		}
+		fmt.Printf("Downloading %d artifacts of %s to %s\n", len(artifacts), build.GetUrl(), dir)
+		downloads := pkg.DownloadArtifacts(context.TODO(), build, artifacts, dir, parallel)
+		failed := 0
+		for _, download := range downloads {
+			if download.Err != nil {
+				failed++
+				fmt.Println(Red("FAILED"), download.Artifact.Path, download.Err)
+			} else if !structuredOutput() {
+				fmt.Println(Green("SAVED"), download.File)
+			}
+		}
+		if structuredOutput() {
+			printStructured(downloads)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func printArtifacts(artifacts []pkg.BuildArtifact) {
+	list := table.NewWriter()
+	list.AppendHeader(table.Row{"ARTIFACT", "SIZE"})
+	for _, artifact := range artifacts {
+		list.AppendRow(table.Row{artifact.Path, formatSize(artifact.Size)})
+	}
+	fmt.Println(list.Render())
+}
+
+// formatSize prints a number of bytes the way ls -h does.
+func formatSize(size int64) string {
+	if size < 0 {
+		return "?"
+	}
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+
+	artifactsCmd.Flags().Bool("download", false, "download the artifacts")
+	artifactsCmd.Flags().StringArray("glob", nil, "only artifacts whose path or file name match this pattern (repeatable)")
+	artifactsCmd.Flags().String("dir", ".", "directory to download the artifacts to")
+	artifactsCmd.Flags().Int("parallel", 4, "how many artifacts to fetch at once")
+	artifactsCmd.Flags().Bool("last-successful", false, "use the last successful build of the job")
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg"
+)
+
+// fakeClient serves folders from a map of path to jobs. Calling anything
+// else panics on the nil Client.
+type fakeClient struct {
+	pkg.Client
+	folders map[string][]gojenkins.InnerJob
+}
+
+func (c *fakeClient) ListJobs(ctx context.Context, path string) ([]gojenkins.InnerJob, error) {
+	return c.folders[path], nil
+}
+
+func (c *fakeClient) Teams(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (c *fakeClient) RunScript(ctx context.Context, team string, script string) (string, error) {
+	return "", nil
+}
+
+// executeWith runs goose in this process against client and returns what it
+// printed. The job index, the history, the home directory and the config
+// file are kept in a temporary directory.
+func executeWith(t *testing.T, client pkg.Client, args ...string) string {
+	dir, err := ioutil.TempDir("", "goose-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pkg.SetStoreDir(dir)
+	defer pkg.SetStoreDir(os.TempDir())
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", home)
+	config := filepath.Join(dir, ".goose.yaml")
+	if err := ioutil.WriteFile(config, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	args = append([]string{"--config", config}, args...)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	rootCmd.SetArgs(args)
+	defer rootCmd.SetArgs(nil)
+	ExecuteWith(client)
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestExecuteWithClient(t *testing.T) {
+	client := &fakeClient{folders: map[string][]gojenkins.InnerJob{
+		"":            {{Name: "example"}, {Name: "other"}},
+		"example/app": {{Name: "master"}, {Name: "feature%2Flogin"}},
+	}}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "TestTeams", args: []string{"jobs"}, want: []string{"example", "other"}},
+		{name: "TestFolder", args: []string{"jobs", "example/app"}, want: []string{"master", "feature%2Flogin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := executeWith(t, client, tt.args...)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want+"\n") {
+					t.Errorf("goose %v printed %q, want %s", tt.args, out, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var contextName string
+
+// contextCmd represents the context command
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the jenkins instances goose talks to",
+	Long: `A context is a named jenkins with the user to log in as, where to get their api key and the topology
+	of its controllers. Contexts are kept in the goose config file.
+
+	goose uses the context given with --context, else the one named by 'context' in a .goose.yaml at the root
+	of the current git repo, else the one picked with 'goose context use'. Without any, the JENKINS_* variables
+	are used.`,
+	// Contexts live in the config file, there's no need to reach jenkins.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the contexts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles := loadProfiles()
+		current, source := currentContext()
+		if structuredOutput() {
+			documents := []ContextDocument{}
+			for _, profile := range profiles {
+				credentials := profile.CredentialSource()
+				profile.APIKey = ""
+				documents = append(documents, ContextDocument{Profile: profile, APIKeySource: credentials, Current: profile.Name == current})
+			}
+			printStructured(documents)
+			return
+		}
+		if len(profiles) == 0 {
+			fmt.Println("No contexts. Use 'goose context add <name>' to add one, the JENKINS_* variables are used until then.")
+			return
+		}
+
+		contextTable := table.NewWriter()
+		contextTable.AppendHeader(table.Row{"CURRENT", "NAME", "URL", "USER", "API KEY", "TOPOLOGY"})
+		for _, profile := range profiles {
+			marker := ""
+			if profile.Name == current {
+				marker = "*"
+			}
+			topology := profile.Topology.Kind
+			if topology == "" {
+				topology = pkg.TopologyTeams
+			}
+			contextTable.AppendRow(table.Row{marker, profile.Name, profile.URL, profile.User, profile.CredentialSource(), topology})
+		}
+		fmt.Println(contextTable.Render())
+		if current != "" {
+			fmt.Println("Current context picked by", source)
+		}
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch to a context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, ok := pkg.FindProfile(loadProfiles(), args[0]); !ok {
+			log.Fatalf("no context named %s, see 'goose context list'", args[0])
+		}
+		if err := saveConfig("current-context", args[0]); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print("Switched to context ", Cyan(args[0]), "\n")
+		if root := pkg.GetCurrentRepoRoot(); root != "" {
+			if name := repoContext(root); name != "" && name != args[0] {
+				fmt.Println(Yellow("This repo's .goose.yaml picks context"), Cyan(name), Yellow("instead"))
+			}
+		}
+	},
+}
+
+var contextAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or replace a context",
+	Long: `add saves a context under a name, replacing the context of that name if there is one.
+
+	goose context add prod --url https://ci.mycompany.com --login-url https://ci.mycompany.com/cjoc \
+		--user me@mycompany.com --api-key-env PROD_JENKINS_API_KEY
+	goose context add sandbox --url https://sandbox.mycompany.com --topology flat \
+		--user me@mycompany.com --api-key-command 'pass show jenkins/sandbox'
+
+	The folders topology maps folder prefixes to controllers with --controller payments=https://ci.mycompany.com/payments`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		profile := pkg.Profile{Name: args[0]}
+		profile.URL, _ = cmd.Flags().GetString("url")
+		profile.LoginURL, _ = cmd.Flags().GetString("login-url")
+		profile.User, _ = cmd.Flags().GetString("user")
+		profile.APIKeyEnv, _ = cmd.Flags().GetString("api-key-env")
+		profile.APIKeyCommand, _ = cmd.Flags().GetString("api-key-command")
+		profile.Topology.Kind, _ = cmd.Flags().GetString("topology")
+		controllers, _ := cmd.Flags().GetStringArray("controller")
+		for _, controller := range controllers {
+			kv := strings.SplitN(controller, "=", 2)
+			if len(kv) != 2 {
+				log.Fatalf("--controller %s is not FOLDER=URL", controller)
+			}
+			if profile.Topology.Controllers == nil {
+				profile.Topology.Controllers = map[string]string{}
+			}
+			profile.Topology.Controllers[kv[0]] = kv[1]
+		}
+		if err := profile.Validate(); err != nil {
+			log.Fatal(err)
+		}
+
+		profiles := loadProfiles()
+		_, replaced := pkg.FindProfile(profiles, profile.Name)
+		if err := saveConfig("contexts", pkg.SaveProfile(profiles, profile)); err != nil {
+			log.Fatal(err)
+		}
+		if replaced {
+			fmt.Print("Replaced context ", Cyan(profile.Name), "\n")
+		} else {
+			fmt.Print("Added context ", Cyan(profile.Name), ". Switch to it with ", Green("goose"), " context use ", Cyan(profile.Name), "\n")
+		}
+	},
+}
+
+// ContextDocument is a context in the output of goose context list. The
+// api key itself is never printed.
+type ContextDocument struct {
+	pkg.Profile  `yaml:",inline"`
+	APIKeySource string `json:"apiKeySource" yaml:"api-key-source"`
+	Current      bool   `json:"current" yaml:"current"`
+}
+
+func loadProfiles() []pkg.Profile {
+	var profiles []pkg.Profile
+	if err := viper.UnmarshalKey("contexts", &profiles); err != nil {
+		log.Fatalf("could not read contexts from %s: %v", viper.ConfigFileUsed(), err)
+	}
+	return profiles
+}
+
+// currentContext returns the name of the context to use and what picked
+// it: --context, the .goose.yaml at the root of the git repo, or the
+// config file. The name is empty when no context is picked.
+func currentContext() (string, string) {
+	if contextName != "" {
+		return contextName, "--context"
+	}
+	if root := pkg.GetCurrentRepoRoot(); root != "" {
+		if name := repoContext(root); name != "" {
+			return name, filepath.Join(root, ".goose.yaml")
+		}
+	}
+	return viper.GetString("current-context"), viper.ConfigFileUsed()
+}
+
+// repoContext reads the context named in the .goose.yaml at the root of a
+// repo.
+func repoContext(root string) string {
+	file := filepath.Join(root, ".goose.yaml")
+	if used, err := filepath.Abs(viper.ConfigFileUsed()); err == nil && used == file {
+		// The repo is the home directory, its file is the config itself
+		return ""
+	}
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+	repoConfig := viper.New()
+	repoConfig.SetConfigFile(file)
+	if err := repoConfig.ReadInConfig(); err != nil {
+		log.Fatalf("could not read %s: %v", file, err)
+	}
+	return repoConfig.GetString("context")
+}
+
+// currentProfile returns the context to use, if one is picked.
+func currentProfile() (pkg.Profile, bool) {
+	name, source := currentContext()
+	if name == "" {
+		return pkg.Profile{}, false
+	}
+	profile, ok := pkg.FindProfile(loadProfiles(), name)
+	if !ok {
+		log.Fatalf("no context named %s (picked by %s), see 'goose context list'", name, source)
+	}
+	return profile, true
+}
+
+// currentTopology is the topology of the current context, or the one
+// configured for the JENKINS_* variables.
+func currentTopology() pkg.Topology {
+	if profile, ok := currentProfile(); ok {
+		return profile.JenkinsTopology()
+	}
+	return loadTopology()
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextAddCmd)
+
+	contextAddCmd.Flags().String("url", "", "root url of jenkins, e.g. https://ci.mycompany.com")
+	contextAddCmd.Flags().String("login-url", "", "url listing the teams, needed by the teams topology")
+	contextAddCmd.Flags().String("user", "", "user to log in as")
+	contextAddCmd.Flags().String("api-key-env", "", "environment variable holding the api key")
+	contextAddCmd.Flags().String("api-key-command", "", "shell command printing the api key")
+	contextAddCmd.Flags().String("topology", pkg.TopologyTeams, "layout of the controllers: teams, flat or folders")
+	contextAddCmd.Flags().StringArray("controller", []string{}, "FOLDER=URL controller of a folder prefix, for the folders topology")
+	contextAddCmd.MarkFlagRequired("url")
+	contextAddCmd.MarkFlagRequired("user")
+}
@@ -0,0 +1,103 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	tm "github.com/buger/goterm"
+	"github.com/jedib0t/go-pretty/table"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+)
+
+const lastLineWidth = 60
+
+// showDashboard redraws the status of every run each second until done is
+// closed.
+func showDashboard(status *pkg.BatchStatus, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		tm.Clear()
+		tm.MoveCursor(1, 1)
+		tm.Println(renderDashboard(status.Snapshot()))
+		tm.Flush()
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func renderDashboard(runs []pkg.RunStatus) string {
+	dashboard := table.NewWriter()
+	dashboard.AppendHeader(table.Row{"RUN", "STATUS", "BUILD", "ELAPSED", "LAST LINE"})
+	for _, run := range runs {
+		lastLine := run.LastLine
+		if runes := []rune(lastLine); len(runes) > lastLineWidth {
+			lastLine = string(runes[:lastLineWidth-3]) + "..."
+		}
+		dashboard.AppendRow(table.Row{run.Tag, colorStatus(run.Status), buildNumber(run), run.Elapsed().Round(time.Second), lastLine})
+	}
+	return dashboard.Render()
+}
+
+// printSummary prints the final state of every run and how many of them
+// ended in each status.
+func printSummary(runs []pkg.RunStatus) {
+	summary := table.NewWriter()
+	summary.AppendHeader(table.Row{"RUN", "STATUS", "BUILD", "DURATION", "URL"})
+	counts := map[string]int{}
+	for _, run := range runs {
+		counts[run.Status]++
+		summary.AppendRow(table.Row{run.Tag, colorStatus(run.Status), buildNumber(run), run.Elapsed().Round(time.Second), run.URL})
+	}
+	fmt.Println(summary.Render())
+	fmt.Print(Green(counts[pkg.RunSuccess]), " succeeded, ", Red(counts[pkg.RunFailure]), " failed, ", Yellow(counts[pkg.RunCancelled]), " cancelled\n")
+}
+
+func colorStatus(status string) Value {
+	switch status {
+	case pkg.RunSuccess:
+		return Green(status)
+	case pkg.RunFailure:
+		return Red(status)
+	case pkg.RunRunning:
+		return Cyan(status)
+	case pkg.RunCancelled:
+		return Yellow(status)
+	default:
+		return White(status)
+	}
+}
+
+func buildNumber(run pkg.RunStatus) string {
+	if run.Build == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("#%d", run.Build)
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rallyhealth/goose/pkg"
+)
+
+func TestRenderDashboardTruncatesByRune(t *testing.T) {
+	lastLine := strings.Repeat("é", lastLineWidth+10)
+	rendered := renderDashboard([]pkg.RunStatus{{Tag: "run", Status: pkg.RunRunning, LastLine: lastLine}})
+	if !utf8.ValidString(rendered) {
+		t.Fatalf("renderDashboard() produced invalid UTF-8: %q", rendered)
+	}
+	want := strings.Repeat("é", lastLineWidth-3) + "..."
+	if !strings.Contains(rendered, want) {
+		t.Errorf("renderDashboard() = %s, want last line %s", rendered, want)
+	}
+}
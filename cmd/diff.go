@@ -0,0 +1,204 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/kireledan/gojenkins"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// DiffDocument is printed by `goose diff` with --output json or yaml.
+type DiffDocument struct {
+	A                     BuildDocument       `json:"a" yaml:"a"`
+	B                     BuildDocument       `json:"b" yaml:"b"`
+	DurationChangeSeconds float64             `json:"durationChangeSeconds" yaml:"durationChangeSeconds"`
+	Parameters            []pkg.ValueChange   `json:"parameters" yaml:"parameters"`
+	Revisions             []pkg.ValueChange   `json:"revisions" yaml:"revisions"`
+	Changes               []pkg.Change        `json:"changes" yaml:"changes"`
+	TestsA                *pkg.TestReport     `json:"testsA,omitempty" yaml:"testsA,omitempty"`
+	TestsB                *pkg.TestReport     `json:"testsB,omitempty" yaml:"testsB,omitempty"`
+	Tests                 *pkg.TestComparison `json:"tests,omitempty" yaml:"tests,omitempty"`
+	Console               []pkg.DiffLine      `json:"console" yaml:"console"`
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [job] <buildA> <buildB>",
+	Short: "Compare two builds of a job",
+	Long: `diff shows what changed from buildA to buildB: parameters, the revisions they checked out, the commits built in between,
+	their durations, their test results and their console output.
+
+	Timestamps and build numbers are stripped from the console before comparing it, and unchanged lines further than
+	--context lines from a change are left out.`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		numberA, errA := strconv.ParseInt(args[len(args)-2], 10, 64)
+		numberB, errB := strconv.ParseInt(args[len(args)-1], 10, 64)
+		if errA != nil || errB != nil {
+			log.Fatal("diff needs two build numbers")
+		}
+		thejob, jobPath := pkg.AutoLocateJob(args[:len(args)-2], "", jenkins(cmd))
+		if thejob == nil {
+			log.Fatalf("could not find job %s", jobPath)
+		}
+		buildA, err := thejob.GetBuild(context.TODO(), numberA)
+		if err != nil {
+			log.Fatalf("could not find build %d of %s: %v", numberA, jobPath, err)
+		}
+		buildB, err := thejob.GetBuild(context.TODO(), numberB)
+		if err != nil {
+			log.Fatalf("could not find build %d of %s: %v", numberB, jobPath, err)
+		}
+
+		lines, _ := cmd.Flags().GetInt("context")
+		diff, err := pkg.DiffBuilds(context.TODO(), thejob, buildA, buildB, lines)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if structuredOutput() {
+			printStructured(newDiffDocument(diff))
+			return
+		}
+		printDiff(buildA, buildB, diff)
+	},
+}
+
+func newDiffDocument(diff *pkg.BuildDiff) DiffDocument {
+	return DiffDocument{
+		A:                     newBuildDocument(diff.A),
+		B:                     newBuildDocument(diff.B),
+		DurationChangeSeconds: diff.DurationChange().Seconds(),
+		Parameters:            diff.Parameters,
+		Revisions:             diff.Revisions,
+		Changes:               diff.Changes,
+		TestsA:                diff.TestsA,
+		TestsB:                diff.TestsB,
+		Tests:                 diff.Tests,
+		Console:               diff.Console,
+	}
+}
+
+func printDiff(buildA *gojenkins.Build, buildB *gojenkins.Build, diff *pkg.BuildDiff) {
+	builds := table.NewWriter()
+	builds.AppendHeader(table.Row{"", "BUILD", "RESULT", "DURATION", "URL"})
+	builds.AppendRow(table.Row{"A", diff.A.Number, colorResult(diff.A.Result), diff.A.Duration.Round(time.Second), buildA.GetUrl()})
+	builds.AppendRow(table.Row{"B", diff.B.Number, colorResult(diff.B.Result), diff.B.Duration.Round(time.Second), buildB.GetUrl()})
+	fmt.Println(builds.Render())
+	fmt.Println("B took", describeDurationChange(diff.DurationChange()))
+
+	printValueChanges("Parameters", diff.Parameters)
+	printValueChanges("Revisions", diff.Revisions)
+
+	fmt.Println()
+	fmt.Println(Bold("Changes"))
+	if len(diff.Changes) == 0 {
+		fmt.Println("No commits built in between")
+	}
+	for _, change := range diff.Changes {
+		fmt.Printf("#%d %s %s %s\n", change.Build, Yellow(shortCommit(change.CommitID)), change.Message, Faint("("+change.Author+")"))
+	}
+
+	fmt.Println()
+	fmt.Println(Bold("Tests"))
+	switch {
+	case diff.TestsA == nil && diff.TestsB == nil:
+		fmt.Println("Neither build has a test report")
+	case diff.Tests == nil:
+		fmt.Println("Only one of the builds has a test report")
+	default:
+		tests := table.NewWriter()
+		tests.AppendHeader(table.Row{"", "PASSED", "FAILED", "SKIPPED"})
+		tests.AppendRow(table.Row{"A", diff.TestsA.Passed, diff.TestsA.Failed, diff.TestsA.Skipped})
+		tests.AppendRow(table.Row{"B", diff.TestsB.Passed, diff.TestsB.Failed, diff.TestsB.Skipped})
+		fmt.Println(tests.Render())
+		for _, name := range diff.Tests.NewFailures {
+			fmt.Println(Red("NEW FAILURE"), name)
+		}
+		for _, name := range diff.Tests.Fixed {
+			fmt.Println(Green("FIXED"), name)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(Bold("Console"))
+	if len(diff.Console) == 0 {
+		fmt.Println("No differences")
+	}
+	for _, line := range diff.Console {
+		switch line.Kind {
+		case pkg.LineAdded:
+			fmt.Println(Green("+ " + line.Text))
+		case pkg.LineRemoved:
+			fmt.Println(Red("- " + line.Text))
+		case pkg.LineSkipped:
+			fmt.Println(Cyan("@@ " + line.Text + " @@"))
+		default:
+			fmt.Println("  " + line.Text)
+		}
+	}
+}
+
+func printValueChanges(title string, changes []pkg.ValueChange) {
+	fmt.Println()
+	fmt.Println(Bold(title))
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+		return
+	}
+	values := table.NewWriter()
+	values.AppendHeader(table.Row{"NAME", "A", "B"})
+	for _, change := range changes {
+		values.AppendRow(table.Row{change.Name, change.A, change.B})
+	}
+	fmt.Println(values.Render())
+}
+
+func describeDurationChange(change time.Duration) string {
+	if change < 0 {
+		return fmt.Sprintf("%s less than A", (-change).Round(time.Second))
+	}
+	return fmt.Sprintf("%s more than A", change.Round(time.Second))
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().Int("context", 3, "unchanged console lines to show around each change")
+}
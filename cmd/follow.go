@@ -0,0 +1,76 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// followOptions are the ways run, rerun and latest can follow a build.
+type followOptions struct {
+	abortOnInterrupt bool
+	stages           bool
+	approve          bool
+}
+
+func addFollowFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("abort-on-interrupt", false, "on Ctrl-C, offer to abort the build on jenkins")
+	cmd.Flags().Bool("stages", false, "show the pipeline stages instead of the console")
+	cmd.Flags().Bool("approve", false, "prompt for the pipeline's input steps while following it")
+}
+
+func followOptionsFromFlags(cmd *cobra.Command) followOptions {
+	var opts followOptions
+	opts.abortOnInterrupt, _ = cmd.Flags().GetBool("abort-on-interrupt")
+	opts.stages, _ = cmd.Flags().GetBool("stages")
+	opts.approve, _ = cmd.Flags().GetBool("approve")
+	return opts
+}
+
+// follow returns how a running build is shown.
+func (o followOptions) follow(client pkg.Client) pkg.FollowFunc {
+	handle := o.inputHandler()
+	if o.stages {
+		return func(ctx context.Context, B *gojenkins.Build) error {
+			return followStages(ctx, B, handle)
+		}
+	}
+	return func(ctx context.Context, B *gojenkins.Build) error {
+		return pkg.FollowBuildWithInputs(ctx, client, B, os.Stdout, handle)
+	}
+}
+
+// inputHandler returns how input steps are answered while following, or nil
+// to leave them alone.
+func (o followOptions) inputHandler() pkg.InputHandler {
+	if o.approve {
+		return promptInput
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the builds goose has started",
+	Long: `history lists every build started with run or runlist, newest first.
+
+	Filter the list with --job, --repo, --result, --since and --until, for example:
+
+	goose history --job Example/Folder --result FAILURE --since 2021-03-01
+
+	Use 'goose history show <id>' to see everything recorded about one build.`,
+	// The history is local, there's no need to reach jenkins.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := historyFilterFromFlags(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		records, err := pkg.ListHistory(filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		if limit > 0 && len(records) > limit {
+			records = records[:limit]
+		}
+		if structuredOutput() {
+			printStructured(records)
+			return
+		}
+		if len(records) == 0 {
+			fmt.Println("No builds found.")
+			return
+		}
+
+		historyTable := table.NewWriter()
+		historyTable.AppendHeader(table.Row{"ID", "STARTED", "JOB", "BUILD", "RESULT", "DURATION"})
+		for _, record := range records {
+			historyTable.AppendRow(table.Row{record.ID, record.Started.Format("2006-01-02 15:04"), record.Job, record.Number, colorResult(record.Result), recordDuration(record)})
+		}
+		fmt.Println(historyTable.Render())
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show everything recorded about a build",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		record, err := pkg.GetHistory(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if structuredOutput() {
+			printStructured(record)
+			return
+		}
+
+		fmt.Print("Job: ", Cyan(record.Job), "\n")
+		fmt.Print("Build: ", Cyan(record.Number), " ", record.URL, "\n")
+		fmt.Print("Result: ", colorResult(record.Result), "\n")
+		fmt.Print("Started: ", record.Started.Format(time.RFC1123), "\n")
+		fmt.Print("Duration: ", recordDuration(record), "\n")
+		if record.Repo != "" {
+			fmt.Print("Repo: ", record.Repo, " ", record.Commit, "\n")
+		}
+
+		names := make([]string, 0, len(record.Parameters))
+		for name := range record.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		paramTable := table.NewWriter()
+		paramTable.AppendHeader(table.Row{"PARAMETER", "VALUE"})
+		for _, name := range names {
+			value := record.Parameters[name]
+			if value == pkg.HiddenValue {
+				paramTable.AppendRow(table.Row{name, Faint(value)})
+				continue
+			}
+			paramTable.AppendRow(table.Row{name, value})
+		}
+		fmt.Println(paramTable.Render())
+	},
+}
+
+func historyFilterFromFlags(cmd *cobra.Command) (pkg.HistoryFilter, error) {
+	var filter pkg.HistoryFilter
+	var err error
+	filter.Job, _ = cmd.Flags().GetString("job")
+	filter.Repo, _ = cmd.Flags().GetString("repo")
+	filter.Result, _ = cmd.Flags().GetString("result")
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		if filter.Since, err = parseHistoryDate(since); err != nil {
+			return filter, err
+		}
+	}
+	if until, _ := cmd.Flags().GetString("until"); until != "" {
+		if filter.Until, err = parseHistoryDate(until); err != nil {
+			return filter, err
+		}
+		if len(until) == len("2006-01-02") {
+			// A plain date includes the whole day.
+			filter.Until = filter.Until.Add(24*time.Hour - time.Nanosecond)
+		}
+	}
+	return filter, nil
+}
+
+func parseHistoryDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("could not parse date %s, use 2006-01-02 or RFC3339", value)
+	}
+	return t, nil
+}
+
+func colorResult(result string) Value {
+	switch result {
+	case "":
+		return Cyan("UNFINISHED")
+	case "SUCCESS":
+		return Green(result)
+	case "UNSTABLE", "ABORTED", pkg.ResultDetached:
+		return Yellow(result)
+	default:
+		return Red(result)
+	}
+}
+
+func recordDuration(record pkg.HistoryRecord) string {
+	if record.Finished.IsZero() {
+		return "-"
+	}
+	return record.Finished.Sub(record.Started).Round(time.Second).String()
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+
+	historyCmd.Flags().String("job", "", "only builds of jobs containing this path")
+	historyCmd.Flags().String("repo", "", "only builds started from repos containing this")
+	historyCmd.Flags().String("result", "", "only builds with this result, e.g. FAILURE")
+	historyCmd.Flags().String("since", "", "only builds started on or after this date")
+	historyCmd.Flags().String("until", "", "only builds started on or before this date")
+	historyCmd.Flags().Int("limit", 0, "show at most this many builds")
+}
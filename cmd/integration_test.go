@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/rallyhealth/goose/pkg/fakejenkins"
+)
+
+// The integration tests run goose end to end against a fake jenkins. goose
+// exits on errors, so each command runs in its own process: the test binary
+// re-executed with gooseProcess set.
+const gooseProcess = "GOOSE_INTEGRATION_PROCESS"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(gooseProcess) == "1" {
+		Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// gooseEnv is a fake jenkins with a home, temp and working directory of its
+// own for goose to run in.
+type gooseEnv struct {
+	server *fakejenkins.Server
+	dir    string
+	// workDir is where goose runs, the root of dir unless changed
+	workDir string
+}
+
+func newGooseEnv(t *testing.T) *gooseEnv {
+	dir, err := ioutil.TempDir("", "goose-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"home", "tmp", "work"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := gojenkins.ParameterDefinition{Name: "env", Type: pkg.ChoiceParameter, Choices: []string{"staging", "prod"}}
+	env.DefaultParameterValue.Value = "staging"
+	dryRun := gojenkins.ParameterDefinition{Name: "dry_run", Type: pkg.BooleanParameter}
+	dryRun.DefaultParameterValue.Value = true
+	server := fakejenkins.New(
+		&fakejenkins.Team{
+			Name: "example",
+			Jobs: []*fakejenkins.Job{{Name: "example", Jobs: []*fakejenkins.Job{
+				{Name: "app", Jobs: []*fakejenkins.Job{
+					{Name: "master", Repo: "https://github.com/example/app.git", Params: []gojenkins.ParameterDefinition{env, dryRun},
+						Console: []string{"deploying to the cluster"},
+						Builds: []*fakejenkins.Build{
+							{Number: 1, Result: "FAILURE", Parameters: map[string]string{"env": "staging", "dry_run": "true"},
+								Console: []string{"Started by user goose", "boom", "Finished: FAILURE"}, Started: time.Now().Add(-time.Hour), Duration: time.Minute},
+						}},
+					{Name: "broken", Repo: "https://github.com/example/app.git", Result: "FAILURE"},
+				}},
+			}}},
+			Queue: []*fakejenkins.QueueItem{
+				{ID: 77, Job: "example/app/master", Why: "Waiting for next available executor", Since: time.Now().Add(-time.Minute),
+					Parameters: map[string]string{"env": "prod"}},
+			},
+		},
+		&fakejenkins.Team{Name: "other", Jobs: []*fakejenkins.Job{{Name: "other"}}},
+	)
+	return &gooseEnv{server: server, dir: dir, workDir: filepath.Join(dir, "work")}
+}
+
+func (g *gooseEnv) close() {
+	g.server.Close()
+	os.RemoveAll(g.dir)
+}
+
+// run runs goose with args and returns its stdout, stderr and exit code.
+func (g *gooseEnv) run(t *testing.T, args ...string) (string, string, int) {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = g.workDir
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "JENKINS_") && !strings.HasPrefix(kv, "HOME=") && !strings.HasPrefix(kv, "TMPDIR=") {
+			cmd.Env = append(cmd.Env, kv)
+		}
+	}
+	for name, value := range g.server.Env() {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.Env = append(cmd.Env,
+		gooseProcess+"=1",
+		"HOME="+filepath.Join(g.dir, "home"),
+		"TMPDIR="+filepath.Join(g.dir, "tmp"),
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	code := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), code
+}
+
+// runJSON runs goose with --output json and decodes what it prints into v.
+func (g *gooseEnv) runJSON(t *testing.T, v interface{}, args ...string) int {
+	// Before any "--", which ends goose's own flags
+	args = append([]string{args[0], "--output", "json"}, args[1:]...)
+	stdout, stderr, code := g.run(t, args...)
+	if err := json.Unmarshal([]byte(stdout), v); err != nil {
+		t.Fatalf("goose %s printed %q: %v\nstderr: %s", strings.Join(args, " "), stdout, err, stderr)
+	}
+	return code
+}
+
+func TestMissingEnvironment(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+	g.server.Close()
+
+	cmd := exec.Command(os.Args[0], "jobs")
+	cmd.Env = []string{gooseProcess + "=1", "HOME=" + filepath.Join(g.dir, "home")}
+	out, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "Please define $JENKINS_EMAIL") {
+		t.Errorf("goose without JENKINS_* = %q, %v", out, err)
+	}
+}
+
+func TestJobsCommand(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	var jobs []JobDocument
+	if code := g.runJSON(t, &jobs, "jobs", "example/app"); code != 0 {
+		t.Fatalf("goose jobs exited with %d", code)
+	}
+	if len(jobs) != 2 || jobs[0].Name != "master" || jobs[0].Color != "red" || jobs[1].Name != "broken" {
+		t.Errorf("goose jobs = %+v", jobs)
+	}
+}
+
+func TestFlatTopology(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+	// The team controller on its own is a plain jenkins
+	config := fmt.Sprintf("topology:\n  kind: flat\n  root: %s/teams-example\n", g.server.URL)
+	if err := ioutil.WriteFile(filepath.Join(g.dir, "home", ".goose.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []JobDocument
+	if code := g.runJSON(t, &jobs, "jobs"); code != 0 || len(jobs) != 1 || jobs[0].Name != "example" {
+		t.Errorf("goose jobs = %+v, exit %d", jobs, code)
+	}
+	var build BuildDocument
+	if code := g.runJSON(t, &build, "latest", "example/app/master"); code != 0 || build.Number != 1 || build.Result != "FAILURE" {
+		t.Errorf("goose latest = %+v, exit %d", build, code)
+	}
+}
+
+func TestContexts(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	if _, stderr, code := g.run(t, "context", "add", "fake", "--url", g.server.URL, "--login-url", g.server.LoginURL(),
+		"--user", fakejenkins.User, "--api-key-command", "echo "+fakejenkins.Token); code != 0 {
+		t.Fatalf("goose context add fake exited %d: %s", code, stderr)
+	}
+	if _, stderr, code := g.run(t, "context", "add", "broken", "--url", g.server.URL, "--topology", "flat",
+		"--user", fakejenkins.User, "--api-key-env", "GOOSE_TEST_MISSING_KEY"); code != 0 {
+		t.Fatalf("goose context add broken exited %d: %s", code, stderr)
+	}
+	if _, _, code := g.run(t, "context", "use", "nope"); code == 0 {
+		t.Error("goose context use of an unknown context succeeded")
+	}
+	if _, stderr, code := g.run(t, "context", "use", "broken"); code != 0 {
+		t.Fatalf("goose context use exited %d: %s", code, stderr)
+	}
+	config, err := ioutil.ReadFile(filepath.Join(g.dir, "home", ".goose.yaml"))
+	if err != nil || strings.Contains(string(config), "author") || !strings.Contains(string(config), "current-context: broken") {
+		t.Errorf("~/.goose.yaml = %q, %v, want only the contexts", config, err)
+	}
+
+	var contexts []ContextDocument
+	if code := g.runJSON(t, &contexts, "context", "list"); code != 0 || len(contexts) != 2 ||
+		contexts[0].Current || !contexts[1].Current || contexts[1].APIKeySource != "$GOOSE_TEST_MISSING_KEY" {
+		t.Errorf("goose context list = %+v, exit %d", contexts, code)
+	}
+
+	// The current context wins over the JENKINS_* variables
+	if _, _, code := g.run(t, "jobs"); code == 0 {
+		t.Error("goose jobs with a broken context succeeded")
+	}
+	var jobs []JobDocument
+	if code := g.runJSON(t, &jobs, "jobs", "--context", "fake"); code != 0 || len(jobs) != 2 {
+		t.Errorf("goose jobs --context fake = %+v, exit %d", jobs, code)
+	}
+
+	// Every context has a job index of its own, only the user may read
+	stores := filepath.Join(g.dir, "home", ".config", "goose")
+	if info, err := os.Stat(stores); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("the store directory: %v, %v", info, err)
+	}
+	if _, err := os.Stat(filepath.Join(stores, "jenkins-db-context-fake")); err != nil {
+		t.Errorf("the job index of context fake: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(stores, "jenkins-db")); !os.IsNotExist(err) {
+		t.Errorf("the shared job index was used: %v", err)
+	}
+
+	// A repo picks its own context
+	initGitRepo(t, g.workDir, "https://github.com/example/app.git")
+	if err := ioutil.WriteFile(filepath.Join(g.workDir, ".goose.yaml"), []byte("context: fake\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jobs = nil
+	if code := g.runJSON(t, &jobs, "jobs"); code != 0 || len(jobs) != 2 {
+		t.Errorf("goose jobs in a repo using context fake = %+v, exit %d", jobs, code)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	var build BuildDocument
+	code := g.runJSON(t, &build, "run", "example/app/master", "--interactive=false", "--", "--env=prod")
+	if code != 0 {
+		t.Fatalf("goose run exited with %d", code)
+	}
+	if build.Number != 2 || build.Result != "SUCCESS" || build.Parameters["env"] != "prod" || build.Parameters["dry_run"] != "true" {
+		t.Errorf("goose run = %+v", build)
+	}
+	builds := g.server.Builds("example", "example/app/master")
+	if len(builds) != 2 || builds[1].Parameters["env"] != "prod" {
+		t.Errorf("builds on jenkins = %+v", builds)
+	}
+
+	var history []pkg.HistoryRecord
+	if code := g.runJSON(t, &history, "history"); code != 0 || len(history) != 1 || history[0].Number != 2 || history[0].Result != "SUCCESS" {
+		t.Errorf("goose history = %+v, exit %d", history, code)
+	}
+}
+
+func TestRunCommandFollowsConsole(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	stdout, _, code := g.run(t, "run", "example/app/broken", "--interactive=false")
+	if code != 1 {
+		t.Errorf("goose run of a failing job exited with %d, want 1", code)
+	}
+	for _, want := range []string{"Started by user goose", "Finished: FAILURE", "finished with"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("goose run printed %q, want it to contain %q", stdout, want)
+		}
+	}
+}
+
+func TestRunCommandRejectsBadValues(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	_, stderr, code := g.run(t, "run", "example/app/master", "--interactive=false", "--", "--env=qa")
+	if code == 0 || !strings.Contains(stderr, "qa") {
+		t.Errorf("goose run with a bad choice exited with %d: %s", code, stderr)
+	}
+	if builds := g.server.Builds("example", "example/app/master"); len(builds) != 1 {
+		t.Errorf("goose run with a bad choice started a build: %+v", builds)
+	}
+}
+
+func TestRunCommandLocatesJobFromRepo(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+	initGitRepo(t, g.workDir, "git@github.com:example/app.git")
+
+	var build BuildDocument
+	if code := g.runJSON(t, &build, "run", "--interactive=false"); code != 0 {
+		t.Fatalf("goose run from the repo exited with %d", code)
+	}
+	if build.Number != 2 || !strings.Contains(build.URL, "/job/app/job/master/") {
+		t.Errorf("goose run from the repo = %+v", build)
+	}
+}
+
+func TestLatestCommand(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	var build BuildDocument
+	if code := g.runJSON(t, &build, "latest", "example/app/master"); code != 0 {
+		t.Fatalf("goose latest exited with %d", code)
+	}
+	if build.Number != 1 || build.Result != "FAILURE" || build.Building || build.DurationSeconds != 60 {
+		t.Errorf("goose latest = %+v", build)
+	}
+
+	stdout, _, _ := g.run(t, "latest", "example/app/master")
+	if !strings.Contains(stdout, "boom") {
+		t.Errorf("goose latest printed %q, want the console", stdout)
+	}
+}
+
+func TestRerunCommand(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	var build BuildDocument
+	code := g.runJSON(t, &build, "rerun", "example/app/master#1", "--interactive=false", "--set", "dry_run=false")
+	if code != 0 {
+		t.Fatalf("goose rerun exited with %d", code)
+	}
+	if build.Number != 2 || build.Parameters["env"] != "staging" || build.Parameters["dry_run"] != "false" {
+		t.Errorf("goose rerun = %+v", build)
+	}
+}
+
+func TestQueueCommands(t *testing.T) {
+	g := newGooseEnv(t)
+	defer g.close()
+
+	var items []pkg.QueueItem
+	if code := g.runJSON(t, &items, "queue"); code != 0 {
+		t.Fatalf("goose queue exited with %d", code)
+	}
+	if len(items) != 1 || items[0].ID != 77 || items[0].Team != "example" || items[0].Job != "example/app/master" || items[0].Parameters["env"] != "prod" {
+		t.Errorf("goose queue = %+v", items)
+	}
+
+	stdout, stderr, code := g.run(t, "queue", "cancel", "77")
+	if code != 0 || !strings.Contains(stdout, "Cancelled") {
+		t.Errorf("goose queue cancel exited with %d: %s %s", code, stdout, stderr)
+	}
+	if queue := g.server.Queue("example"); len(queue) != 0 {
+		t.Errorf("queue after cancelling = %+v", queue)
+	}
+}
+
+// initGitRepo makes dir a git repo with one commit on master whose origin is
+// remote.
+func initGitRepo(t *testing.T, dir string, remote string) {
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := repo.Worktree()
+	worktree.Add("README.md")
+	signature := &object.Signature{Name: "goose", Email: "goose@example.com", When: time.Now()}
+	if _, err := worktree.Commit("Initial commit", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/rallyhealth/goose/pkg"
 	"github.com/kireledan/gojenkins"
 	"github.com/spf13/cobra"
 )
@@ -39,14 +38,19 @@ var jobsCmd = &cobra.Command{
 	Use:   "jobs",
 	Short: "List jobs in a jenkins directory",
 	Long: `List out the jobs in a jenkins directory. By default this will be the root of jenkins and list out the
-	team jenkins.`,
+	team jenkins.
+
+	With --output json or yaml every job is listed with its name, url and color.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 0 {
-			job, err := pkg.GetNestedJob(Jenky, args[0])
+			jobs, err := jenkins(cmd).ListJobs(context.TODO(), args[0])
 			if err != nil {
 				log.Fatal(err)
 			}
-			jobs := job.Raw.Jobs
+			if structuredOutput() {
+				printStructured(newJobDocuments(jobs))
+				return
+			}
 			if len(jobs) == 0 {
 				fmt.Println("There are no jobs within this folder.")
 				return
@@ -55,10 +59,14 @@ var jobsCmd = &cobra.Command{
 				fmt.Println(job.Name)
 			}
 		} else {
-			jobs, err := Jenky.GetAllJobNames(context.TODO())
+			jobs, err := jenkins(cmd).ListJobs(context.TODO(), "")
 			if err != nil {
 				gojenkins.Error.Println(err)
 			}
+			if structuredOutput() {
+				printStructured(newJobDocuments(jobs))
+				return
+			}
 			for _, job := range jobs {
 				fmt.Println(job.Name)
 			}
@@ -41,27 +41,56 @@ var latestCmd = &cobra.Command{
 	Use:   "latest",
 	Short: "Grabs the latest build of a jenkins job",
 	Long: `latest will print out the URL of the last running or currently running build of a given job.
-	If the job is still running, the output will be streamed to the terminal. If not, the output from the build will be printed in the terminal.`,
+	If the job is still running, the output will be streamed to the terminal. If not, the output from the build will be printed in the terminal.
+
+	With --output json or yaml only the build's metadata is printed.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		_, jobPath := pkg.AutoLocateJob(args, "", Jenky)
+		client := jenkins(cmd)
+		_, jobPath := pkg.AutoLocateJob(args, "", client)
 
-		jenkinsJob, err := pkg.GetNestedJob(Jenky, jobPath)
+		b, err := client.GetBuild(context.TODO(), jobPath, 0)
 		if err != nil {
 			log.Fatal(err)
 		}
-		b, err := jenkinsJob.GetLastBuild(context.TODO())
-		if err != nil {
-			log.Fatal(err)
+		if structuredOutput() {
+			printStructured(newBuildDocument(pkg.NewBuildResult(b)))
+			return
 		}
 		fmt.Println(b.GetUrl())
+		opts := followOptionsFromFlags(cmd)
+		if opts.stages {
+			ctx, cancel := interruptContext(opts.abortOnInterrupt)
+			defer cancel()
+			if err := followStages(ctx, b, opts.inputHandler()); err != nil {
+				if ctx.Err() != nil {
+					offerAbort(b)
+				}
+				log.Fatal(err)
+			}
+			return
+		}
 		if b.IsRunning(context.TODO()) {
+			ctx, cancel := interruptContext(opts.abortOnInterrupt)
+			defer cancel()
+			handle := opts.inputHandler()
+			handled := map[string]bool{}
 			offset := int64(0)
 			for b.IsRunning(context.TODO()) {
-				time.Sleep(time.Second * 2)
-				resp, _ := b.GetConsoleOutputFromIndex(context.TODO(), offset)
-				offset = resp.Offset
-				if len(resp.Content) > 0 {
-					fmt.Println(resp.Content)
+				select {
+				case <-ctx.Done():
+					offerAbort(b)
+				case <-time.After(time.Second * 2):
+				}
+				if handle != nil {
+					if err := pkg.HandleInputs(ctx, b, handled, handle); err != nil {
+						fmt.Println("Not watching for input steps anymore:", err)
+						handle = nil
+					}
+				}
+				chunk, _ := client.GetConsole(context.TODO(), b, offset)
+				offset = chunk.Offset
+				if len(chunk.Text) > 0 {
+					fmt.Println(chunk.Text)
 				}
 			}
 		} else {
@@ -82,4 +111,5 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	latestCmd.Flags().BoolVarP(&logs, "logs", "l", false, "show logs")
+	addFollowFlags(latestCmd)
 }
@@ -0,0 +1,70 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg"
+)
+
+// locateBuild finds the build named by [job] [build#] arguments. The job is
+// located like run does when it's left out, and the last build is used
+// when no number is given.
+func locateBuild(client pkg.Client, args []string) (*gojenkins.Job, *gojenkins.Build) {
+	var number int64
+	if len(args) > 0 {
+		if n, err := strconv.ParseInt(args[len(args)-1], 10, 64); err == nil {
+			number = n
+			args = args[:len(args)-1]
+		}
+	}
+	thejob, jobPath := pkg.AutoLocateJob(args, "", client)
+	if thejob == nil {
+		log.Fatalf("could not find job %s", jobPath)
+	}
+
+	build, err := client.GetBuild(context.TODO(), jobPath, number)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return thejob, build
+}
+
+// locateLastSuccessfulBuild finds the last successful build of the job
+// named by args, located like run does when it's left out.
+func locateLastSuccessfulBuild(client pkg.Client, args []string) (*gojenkins.Job, *gojenkins.Build) {
+	thejob, jobPath := pkg.AutoLocateJob(args, "", client)
+	if thejob == nil {
+		log.Fatalf("could not find job %s", jobPath)
+	}
+	build, err := thejob.GetLastSuccessfulBuild(context.TODO())
+	if err != nil || build == nil {
+		log.Fatalf("%s has no successful build: %v", jobPath, err)
+	}
+	return thejob, build
+}
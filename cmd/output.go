@@ -0,0 +1,130 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+var outputFormat string
+
+// structuredOut is where JSON and YAML documents are written. In those modes
+// os.Stdout is pointed at stderr so progress messages, spinners and build
+// logs don't end up in the document.
+var structuredOut io.Writer = os.Stdout
+
+func initOutput() {
+	switch outputFormat {
+	case outputText:
+	case outputJSON, outputYAML:
+		structuredOut = os.Stdout
+		os.Stdout = os.Stderr
+	default:
+		fmt.Printf("Unknown output format '%s'. Use %s, %s or %s\n", outputFormat, outputText, outputJSON, outputYAML)
+		os.Exit(1)
+	}
+}
+
+// structuredOutput reports whether a JSON or YAML document was asked for.
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// printStructured writes v to stdout in the requested format.
+func printStructured(v interface{}) {
+	var err error
+	if outputFormat == outputYAML {
+		err = yaml.NewEncoder(structuredOut).Encode(v)
+	} else {
+		encoder := json.NewEncoder(structuredOut)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(v)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// JobDocument describes a job or folder as listed by `goose jobs`.
+type JobDocument struct {
+	Name  string `json:"name" yaml:"name"`
+	URL   string `json:"url" yaml:"url"`
+	Color string `json:"color" yaml:"color"`
+}
+
+func newJobDocuments(jobs []gojenkins.InnerJob) []JobDocument {
+	docs := make([]JobDocument, 0, len(jobs))
+	for _, job := range jobs {
+		docs = append(docs, JobDocument{Name: job.Name, URL: job.Url, Color: job.Color})
+	}
+	return docs
+}
+
+// SearchDocument maps a git repo to the jobs building it, as printed by
+// `goose search`.
+type SearchDocument map[string][]string
+
+// BuildDocument describes a build, as printed by `goose latest` and
+// `goose run`. Result is empty and Building is true while the build is
+// running.
+type BuildDocument struct {
+	Job             string            `json:"job" yaml:"job"`
+	Number          int64             `json:"number" yaml:"number"`
+	QueueID         int64             `json:"queueId" yaml:"queueId"`
+	URL             string            `json:"url" yaml:"url"`
+	Building        bool              `json:"building" yaml:"building"`
+	Result          string            `json:"result" yaml:"result"`
+	Timestamp       time.Time         `json:"timestamp" yaml:"timestamp"`
+	DurationSeconds float64           `json:"durationSeconds" yaml:"durationSeconds"`
+	Parameters      map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+func newBuildDocument(result *pkg.BuildResult) BuildDocument {
+	return BuildDocument{
+		Job:             result.Job,
+		Number:          result.Number,
+		QueueID:         result.QueueID,
+		URL:             result.URL,
+		Building:        result.Result == "",
+		Result:          result.Result,
+		Timestamp:       result.Started,
+		DurationSeconds: result.Duration.Seconds(),
+		Parameters:      result.Parameters,
+	}
+}
@@ -0,0 +1,194 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/kireledan/gojenkins"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const noPreset = "(job defaults)"
+
+// presetCmd represents the preset command
+var presetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "Manage saved job parameters",
+	Long: `Presets are named sets of parameters for a job, kept in the goose config file.
+
+	Save one with 'goose run --save-preset <name>' and start a build from it with 'goose run --preset <name>'.`,
+	// Presets live in the config file, there's no need to reach jenkins.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var presetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved presets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		job, _ := cmd.Flags().GetString("job")
+		presets := pkg.FindPresets(loadPresets(), job, "")
+		if structuredOutput() {
+			printStructured(presets)
+			return
+		}
+		if len(presets) == 0 {
+			fmt.Println("No presets saved. Use 'goose run --save-preset <name>' to save one.")
+			return
+		}
+
+		presetTable := table.NewWriter()
+		presetTable.AppendHeader(table.Row{"JOB", "NAME", "PARAMETERS"})
+		for _, preset := range presets {
+			presetTable.AppendRow(table.Row{preset.Job, preset.Name, len(preset.Parameters)})
+		}
+		fmt.Println(presetTable.Render())
+	},
+}
+
+var presetShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the parameters of a preset",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		job, _ := cmd.Flags().GetString("job")
+		preset := findPreset(job, args[0])
+		if structuredOutput() {
+			printStructured(preset)
+			return
+		}
+
+		fmt.Print("Job: ", Cyan(preset.Job), "\n")
+		fmt.Print("Preset: ", Cyan(preset.Name), "\n")
+		names := make([]string, 0, len(preset.Parameters))
+		for name := range preset.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		paramTable := table.NewWriter()
+		paramTable.AppendHeader(table.Row{"PARAMETER", "VALUE"})
+		for _, name := range names {
+			paramTable.AppendRow(table.Row{name, preset.Parameters[name]})
+		}
+		fmt.Println(paramTable.Render())
+	},
+}
+
+var presetDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a preset",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		job, _ := cmd.Flags().GetString("job")
+		preset := findPreset(job, args[0])
+		presets, _ := pkg.DeletePresets(loadPresets(), preset.Job, preset.Name)
+		if err := savePresets(presets); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print("Deleted preset ", Cyan(preset.Name), " of ", Cyan(preset.Job), "\n")
+	},
+}
+
+func loadPresets() []pkg.Preset {
+	var presets []pkg.Preset
+	if err := viper.UnmarshalKey("presets", &presets); err != nil {
+		log.Fatalf("could not read presets from %s: %v", viper.ConfigFileUsed(), err)
+	}
+	return presets
+}
+
+func savePresets(presets []pkg.Preset) error {
+	return saveConfig("presets", presets)
+}
+
+// findPreset looks a preset up by name, exiting when it is missing or when
+// presets of several jobs share the name and job is empty.
+func findPreset(job string, name string) pkg.Preset {
+	presets := pkg.FindPresets(loadPresets(), job, name)
+	switch {
+	case len(presets) == 0 && job == "":
+		log.Fatalf("no preset named %s, see 'goose preset list'", name)
+	case len(presets) == 0:
+		log.Fatalf("no preset named %s for %s, see 'goose preset list'", name, job)
+	case len(presets) > 1:
+		log.Fatalf("several jobs have a preset named %s, pick one with --job", name)
+	}
+	return presets[0]
+}
+
+// savePreset stores choices as a preset of job. Passwords are left out.
+func savePreset(job string, name string, params []gojenkins.ParameterDefinition, choices map[string]string) {
+	values := map[string]string{}
+	for _, param := range params {
+		if value, ok := choices[param.Name]; ok && param.Type != pkg.PasswordParameter {
+			values[param.Name] = value
+		}
+	}
+	presets := pkg.SavePreset(loadPresets(), pkg.Preset{Job: job, Name: name, Parameters: values})
+	if err := savePresets(presets); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print("Saved preset ", Cyan(name), ". Next time, type ", Green("goose"), " run ", Cyan(job), " --preset ", Cyan(name), "\n")
+}
+
+// choosePreset offers the presets of job as starting values for the
+// prompts. It returns nil when there are none or none was picked.
+func choosePreset(job string) map[string]string {
+	presets := pkg.FindPresets(loadPresets(), job, "")
+	if len(presets) == 0 {
+		return nil
+	}
+	options := []string{noPreset}
+	for _, preset := range presets {
+		options = append(options, preset.Name)
+	}
+	picked := ""
+	survey.AskOne(&survey.Select{
+		Message: "Start from a preset: ",
+		Options: options,
+	}, &picked)
+	for _, preset := range presets {
+		if preset.Name == picked {
+			return preset.Parameters
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(presetCmd)
+	presetCmd.AddCommand(presetListCmd)
+	presetCmd.AddCommand(presetShowCmd)
+	presetCmd.AddCommand(presetDeleteCmd)
+
+	presetCmd.PersistentFlags().String("job", "", "only presets of this job")
+}
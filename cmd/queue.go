@@ -0,0 +1,151 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "List the builds waiting in the jenkins queue",
+	Long: `queue lists the builds waiting to start in the queues of your teams, with the reason jenkins gives
+	for holding them, how long they have waited and their parameters.
+
+	Use --team to look at some teams only, and 'goose queue cancel <id>' to take a build off the queue.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		items := queueItems(jenkins(cmd), queueTeams(cmd))
+		sort.Slice(items, func(i, j int) bool { return items[i].Since.Before(items[j].Since) })
+		if structuredOutput() {
+			printStructured(items)
+			return
+		}
+		if len(items) == 0 {
+			fmt.Println("The queue is empty.")
+			return
+		}
+
+		queueTable := table.NewWriter()
+		queueTable.AppendHeader(table.Row{"ID", "TEAM", "JOB", "WAITING", "WHY", "PARAMETERS"})
+		for _, item := range items {
+			why := strings.SplitN(item.Why, "\n", 2)[0]
+			if item.Stuck {
+				why = Red(why).String()
+			}
+			queueTable.AppendRow(table.Row{item.ID, item.Team, item.Job, item.Waiting().Round(time.Second), why, formatParameters(item.Parameters)})
+		}
+		fmt.Println(queueTable.Render())
+	},
+}
+
+var queueCancelCmd = &cobra.Command{
+	Use:   "cancel <id>...",
+	Short: "Take builds off the jenkins queue",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		items := queueItems(jenkins(cmd), queueTeams(cmd))
+		for _, arg := range args {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("%s is not a queue id", arg)
+			}
+			matches := []pkg.QueueItem{}
+			for _, item := range items {
+				if item.ID == id {
+					matches = append(matches, item)
+				}
+			}
+			switch len(matches) {
+			case 0:
+				log.Fatalf("no queued build with id %d", id)
+			case 1:
+			default:
+				log.Fatalf("%d is queued on several teams, pick one with --team", id)
+			}
+			if err := jenkins(cmd).CancelQueueItem(context.TODO(), matches[0].Team, id); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print("Cancelled ", Cyan(matches[0].Job), " (", id, ")\n")
+		}
+	},
+}
+
+// queueTeams returns the teams given with --team, or every team.
+func queueTeams(cmd *cobra.Command) []string {
+	teams, _ := cmd.Flags().GetStringArray("team")
+	if len(teams) > 0 {
+		return teams
+	}
+	teams, err := jenkins(cmd).Teams(context.TODO())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return teams
+}
+
+// queueItems lists the queued builds of every team.
+func queueItems(client pkg.Client, teams []string) []pkg.QueueItem {
+	items := []pkg.QueueItem{}
+	for _, team := range teams {
+		queue, err := client.GetQueue(context.TODO(), team)
+		if err != nil {
+			log.Fatal(err)
+		}
+		items = append(items, queue...)
+	}
+	return items
+}
+
+func formatParameters(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, params[name]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueCancelCmd)
+
+	queueCmd.PersistentFlags().StringArray("team", nil, "only this team's queue (repeatable)")
+}
@@ -0,0 +1,113 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// rerunCmd represents the rerun command
+var rerunCmd = &cobra.Command{
+	Use:   "rerun <build URL | job#number>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Run a jenkins job again with the parameters of a previous build",
+	Long: `rerun starts a new build of a job using the parameter values of an earlier build.
+	The build is given either as its URL or as job#number, e.g. Example/Folder/mainbranch#42
+
+	By default the prompts from run are shown, pre-filled with the old values.
+	With --interactive=false the job is started right away. Use --set KEY=VALUE to change a value.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ref, err := currentTopology().ParseBuildRef(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		client := jenkins(cmd)
+		thejob, err := client.GetJob(context.TODO(), ref.Job)
+		if err != nil {
+			log.Fatal(err)
+		}
+		previous, err := thejob.GetBuild(context.TODO(), ref.BuildNumber)
+		if err != nil {
+			log.Fatalf("could not get build %d of %s: %v", ref.BuildNumber, ref.Job, err)
+		}
+		params := pkg.GetJobParameters(client, ref.Job)
+
+		values := map[string]string{}
+		for _, param := range previous.GetParameters() {
+			values[param.Name] = param.Value
+		}
+		sets, _ := cmd.Flags().GetStringArray("set")
+		for _, set := range sets {
+			kv := strings.SplitN(set, "=", 2)
+			if len(kv) != 2 {
+				log.Fatalf("--set %s is not KEY=VALUE", set)
+			}
+			param, ok := pkg.FindParameter(params, kv[0])
+			if !ok {
+				log.Fatalf("%s has no parameter %s", ref.Job, kv[0])
+			}
+			value, err := pkg.CheckParameter(param, kv[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+			values[kv[0]] = value
+		}
+
+		fmt.Print("Rerunning build ", Cyan(ref.BuildNumber), " of ", Cyan(ref.Job), "\n")
+		var choices map[string]string
+		interactive, _ := strconv.ParseBool(cmd.Flag("interactive").Value.String())
+		if interactive {
+			choices = promptParameters(client, thejob, params, values)
+			confirmParameters(thejob, ref.Job, params, choices)
+		} else {
+			// Parameters added to the job since the old build get their defaults
+			choices = map[string]string{}
+			for _, param := range params {
+				choices[param.Name] = pkg.ParameterDefault(param)
+				if value, ok := values[param.Name]; ok {
+					choices[param.Name] = value
+				}
+				fmt.Println(param.Name, displayValue(param, choices[param.Name]))
+			}
+		}
+
+		invokeAndReport(client, thejob, choices, followOptionsFromFlags(cmd))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rerunCmd)
+
+	rerunCmd.Flags().BoolP("interactive", "i", true, "Prompt for parameters, pre-filled with the old values")
+	addFollowFlags(rerunCmd)
+	rerunCmd.Flags().StringArray("set", nil, "Override a parameter with KEY=VALUE (repeatable)")
+}
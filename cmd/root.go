@@ -30,6 +30,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/rallyhealth/goose/pkg"
@@ -41,7 +42,16 @@ import (
 )
 
 var cfgFile string
-var Jenky *gojenkins.Jenkins
+
+// clientKey is where the jenkins client is kept in the context of the
+// commands.
+type clientKey struct{}
+
+// clientHolder lets PersistentPreRun connect a client when none was given to
+// ExecuteWith.
+type clientHolder struct {
+	client pkg.Client
+}
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -70,43 +80,124 @@ var rootCmd = &cobra.Command{
                   '.'.<
                     '-'`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		var err error
-		user := os.Getenv("JENKINS_EMAIL")
-		key := os.Getenv("JENKINS_API_KEY")
-		rootJenkins := os.Getenv("JENKINS_ROOT_URL")
-		loginJenkins := os.Getenv("JENKINS_LOGIN_URL")
-		if user == "" || key == "" || rootJenkins == "" || loginJenkins == "" {
-			fmt.Println("Please define $JENKINS_EMAIL and $JENKINS_API_KEY and $JENKINS_ROOT_URL and $JENKINS_LOGIN_URL")
-			os.Exit(1)
+		holder := cmd.Context().Value(clientKey{}).(*clientHolder)
+		if holder.client == nil {
+			holder.client = connect()
 		}
-		clientWithTimeout := http.Client{
-			Timeout: 3 * time.Second,
+		pkg.RefreshJobIndex(holder.client)
+	},
+}
+
+// connect logs in to the jenkins of the current context, or the one given by
+// the JENKINS_* variables.
+func connect() pkg.Client {
+	user, key, topology := credentials()
+	if err := topology.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	clientWithTimeout := http.Client{
+		Timeout: 3 * time.Second,
+	}
+	Jenky, err := gojenkins.CreateJenkins(&clientWithTimeout, topology.LoginURL(), user, key).Init(context.TODO())
+	if err != nil {
+		fmt.Println("Error connecting to jenkins. Make sure you are able to reach your jenkins instance. Is it on a VPN?")
+		log.Fatal(err)
+	}
+	status, err := Jenky.Poll(context.TODO())
+	if status == 401 {
+		if profile, ok := currentProfile(); ok {
+			log.Fatalf("Invalid credentials. Double check the user and api key of context %s", profile.Name)
 		}
-		Jenky, err = gojenkins.CreateJenkins(&clientWithTimeout, fmt.Sprintf("%s", loginJenkins), user, key).Init(context.TODO())
-		if err != nil {
-			fmt.Println("Error connecting to jenkins. Make sure you are able to reach your jenkins instance. Is it on a VPN?")
+		log.Fatal("Invalid credentials. Double check your jenkins envs JENKINS_EMAIL and JENKINS_API_KEY")
+	}
+	return pkg.NewJenkinsClient(Jenky, topology)
+}
+
+// credentials returns who to log in as and where, from the current context
+// or else the JENKINS_* variables.
+func credentials() (string, string, pkg.Topology) {
+	if profile, ok := currentProfile(); ok {
+		if err := profile.Validate(); err != nil {
 			log.Fatal(err)
 		}
-		status, err := Jenky.Poll(context.TODO())
-		if status == 401 {
-			log.Fatal("Invalid credentials. Double check your jenkins envs JENKINS_EMAIL and JENKINS_API_KEY")
+		key, err := profile.GetAPIKey()
+		if err != nil {
+			log.Fatal(err)
 		}
-		pkg.RefreshJobIndex(Jenky)
+		return profile.User, key, profile.JenkinsTopology()
+	}
+	user := os.Getenv("JENKINS_EMAIL")
+	key := os.Getenv("JENKINS_API_KEY")
+	topology := loadTopology()
+	if user == "" || key == "" || topology.Root == "" {
+		fmt.Println("Please define $JENKINS_EMAIL and $JENKINS_API_KEY and $JENKINS_ROOT_URL and $JENKINS_LOGIN_URL, or add a context with goose context add")
+		os.Exit(1)
+	}
+	return user, key, topology
+}
 
-	},
+// loadTopology reads how jobs are spread over controllers from the topology
+// key of the config. The urls default to $JENKINS_ROOT_URL and
+// $JENKINS_LOGIN_URL.
+func loadTopology() pkg.Topology {
+	var topology pkg.Topology
+	if err := viper.UnmarshalKey("topology", &topology); err != nil {
+		log.Fatalf("could not read the topology from %s: %v", viper.ConfigFileUsed(), err)
+	}
+	if topology.Root == "" {
+		topology.Root = os.Getenv("JENKINS_ROOT_URL")
+	}
+	if topology.Login == "" {
+		topology.Login = os.Getenv("JENKINS_LOGIN_URL")
+	}
+	return topology
+}
+
+// saveConfig sets key in the config file, creating ~/.goose.yaml if there is
+// no config file yet. Only key is written: the file is rewritten from its own
+// content, so defaults and environment variables don't end up in it.
+func saveConfig(key string, value interface{}) error {
+	viper.Set(key, value)
+	filename := viper.ConfigFileUsed()
+	if filename == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return err
+		}
+		filename = filepath.Join(home, ".goose.yaml")
+	}
+	config := viper.New()
+	config.SetConfigFile(filename)
+	if err := config.ReadInConfig(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	config.Set(key, value)
+	return config.WriteConfigAs(filename)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ExecuteWith(nil)
+}
+
+// ExecuteWith runs the commands against client, or against the jenkins given
+// by the JENKINS_* variables when client is nil.
+func ExecuteWith(client pkg.Client) {
+	ctx := context.WithValue(context.Background(), clientKey{}, &clientHolder{client: client})
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// jenkins is the client the commands talk to.
+func jenkins(cmd *cobra.Command) pkg.Client {
+	return cmd.Context().Value(clientKey{}).(*clientHolder).client
+}
+
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initOutput, initConfig, initStores)
 
 	viper.SetDefault("author", "kireledan erik.nadel@rallyhealth.com")
 
@@ -115,6 +206,8 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.goose.yaml)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "context to use instead of the current one, see goose context")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "output format: text, json or yaml")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -146,3 +239,13 @@ func initConfig() {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// initStores keeps the job index and the history of every context apart,
+// and without a context those of every root url.
+func initStores() {
+	if name, _ := currentContext(); name != "" {
+		pkg.UseStore("context-" + name)
+		return
+	}
+	pkg.UseStore(loadTopology().Root)
+}
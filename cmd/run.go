@@ -24,15 +24,21 @@ SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	tm "github.com/buger/goterm"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/jedib0t/go-pretty/table"
+	"github.com/kireledan/gojenkins"
 	. "github.com/logrusorgru/aurora/v3"
+	"github.com/pkg/errors"
 	"github.com/rallyhealth/goose/pkg"
 	"github.com/spf13/cobra"
 )
@@ -48,18 +54,43 @@ var runCmd = &cobra.Command{
 
 	Otherwise, you can specify a job to be run.
 	For example, Example/kireledan//long-branch-name specifies the Job , 'Example' , given the branch kireledan/long-branch-name
-	Note that '/' in git branches are replaced  with '//'`,
+	Note that '/' in git branches are replaced  with '//'
+
+	File parameters take the path of a local file, which is uploaded with the build. Use --file PARAM=path to give it up front.
+
+	--preset <name> starts from parameters saved with --save-preset <name>, see 'goose preset'.
+
+	Ctrl-C only stops goose, the build keeps running. With --abort-on-interrupt goose asks whether to abort it on jenkins too.
+	--stages shows the pipeline's stages while it runs instead of its console.
+
+	goose waits for the build to finish and exits with a non-zero status unless it ends in SUCCESS.
+	With --output json or yaml the build log goes to stderr and the build's result is printed to stdout.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		thejob, jobPath := pkg.AutoLocateJob(args, cmd.Flag("branch").Value.String(), Jenky)
+		client := jenkins(cmd)
+		thejob, jobPath := pkg.AutoLocateJob(args, cmd.Flag("branch").Value.String(), client)
 
-		params := pkg.GetJobParameters(Jenky, jobPath)
+		params := pkg.GetJobParameters(client, jobPath)
 		choices := map[string]string{}
 
+		var preset map[string]string
+		if name, _ := cmd.Flags().GetString("preset"); name != "" {
+			preset = findPreset(jobPath, name).Parameters
+		}
+		files, _ := cmd.Flags().GetStringArray("file")
+		uploads := fileFlags(params, files)
+
 		interactive, _ := strconv.ParseBool(cmd.Flag("interactive").Value.String())
 		if !interactive {
 			jobArgs := map[string]*string{}
 			for _, param := range params {
-				tester := cmd.LocalFlags().String(param.Name, fmt.Sprintf("%v", param.DefaultParameterValue.Value), param.Description)
+				defaultValue := pkg.ParameterDefault(param)
+				if value, ok := preset[param.Name]; ok {
+					defaultValue = value
+				}
+				if value, ok := uploads[param.Name]; ok {
+					defaultValue = value
+				}
+				tester := cmd.LocalFlags().String(param.Name, defaultValue, param.Description)
 				jobArgs[param.Name] = tester
 			}
 			err := cmd.LocalFlags().Parse(args)
@@ -67,91 +98,260 @@ var runCmd = &cobra.Command{
 				cmd.LocalFlags().Args()
 				panic(err)
 			}
-			for arg, argstuff := range jobArgs {
-				fmt.Println(arg, *argstuff)
-				choices[arg] = *argstuff
+			for _, param := range params {
+				value, err := pkg.CheckParameter(param, *jobArgs[param.Name])
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(param.Name, displayValue(param, value))
+				choices[param.Name] = value
 			}
 
 		} else {
-			// Pick params
+			if preset == nil {
+				preset = choosePreset(jobPath)
+			}
+			defaults := map[string]string{}
+			for name, value := range preset {
+				defaults[name] = value
+			}
+			for name, value := range uploads {
+				defaults[name] = value
+			}
+			choices = promptParameters(client, thejob, params, defaults)
+			confirmParameters(thejob, jobPath, params, choices)
+		}
 
-			fmt.Println("Please pick your parameters for", thejob.Raw.FullDisplayName)
-			for _, param := range params {
-				if len(param.Choices) > 0 {
-					result := ""
-					prompt := &survey.Select{
-						Message: fmt.Sprintf("%s: ", param.Name),
-						Help:    param.Description,
-						Options: param.Choices,
-						Default: param.DefaultParameterValue.Value,
-					}
-					survey.AskOne(prompt, &result)
-					choices[param.Name] = result
-				} else {
-					result := ""
-					prompt := &survey.Input{
-						Message: fmt.Sprintf("%s: ", param.Name),
-						Help:    param.Description,
-						Default: fmt.Sprintf("%v", param.DefaultParameterValue.Value),
-					}
-					survey.AskOne(prompt, &result)
-					choices[param.Name] = result
-				}
+		if name, _ := cmd.Flags().GetString("save-preset"); name != "" {
+			savePreset(jobPath, name, params, choices)
+		}
+		invokeAndReport(client, thejob, choices, followOptionsFromFlags(cmd))
+	},
+}
 
+// promptParameters asks for every parameter of a job. A value in defaults
+// is offered instead of the parameter's own default. The choices of Active
+// Choices parameters are asked from jenkins using the answers given so far.
+func promptParameters(client pkg.Client, thejob *gojenkins.Job, params []gojenkins.ParameterDefinition, defaults map[string]string) map[string]string {
+	choices := map[string]string{}
+	scriptDenied := false
+	fmt.Println("Please pick your parameters for", thejob.Raw.FullDisplayName)
+	for _, param := range params {
+		defaultValue := pkg.ParameterDefault(param)
+		if pkg.IsDynamicParameter(param) && !scriptDenied {
+			options, selected, err := pkg.ResolveChoices(client, thejob, param, choices)
+			switch {
+			case errors.Cause(err) == pkg.ErrScriptDenied:
+				// Every other Active Choices parameter would fail the same way
+				scriptDenied = true
+				fmt.Println(Yellow("Listing the choices of Active Choices parameters needs the Overall/Administer permission to run a script on jenkins. Type their values instead."))
+			case err != nil:
+				fmt.Println(Yellow(err))
 			}
-			tm.Clear()
-			tm.Flush()
-			paramTable := table.NewWriter()
-			//paramTable.SetOutputMirror(os.Stdout)
-			paramTable.AppendHeader(table.Row{"PARAMETER", "CHOICE"})
-			for name, choice := range choices {
-				if choice == "" {
-					choice = "None"
-				}
-				paramTable.AppendRow(table.Row{name, choice})
+			param.Choices = options
+			if selected != "" {
+				defaultValue = selected
 			}
-			output := paramTable.Render()
-			shortcut := printShortCut(jobPath, choices)
-
-			fmt.Println(shortcut)
-			fmt.Println("----")
-			// Add some content to the box
-			// Note that you can add ANY content, even tables
-			job := strings.Split(thejob.Raw.FullName, "/")[:len(strings.Split(thejob.Raw.FullName, "/"))-1]
-			branch := strings.Split(thejob.Raw.FullName, "/")[len(strings.Split(thejob.Raw.FullName, "/"))-1]
-			fmt.Print("Job: ", Cyan(job), "\n")
-			fmt.Print("Branch: ", Cyan(branch), "\n")
-			fmt.Print(output)
-			fmt.Print("\nPlease confirm these parameters by pressing ", Yellow("[Enter]"), "\n")
-
-			// Move Box to approx center of the screen
-
-			fmt.Scanln()
 		}
+		if value, ok := defaults[param.Name]; ok {
+			defaultValue = value
+		}
+		choices[param.Name] = askParameter(param, defaultValue)
+	}
+	return choices
+}
 
-		pkg.InvokeJob(Jenky, thejob, choices)
-	},
+// fileFlags reads --file PARAM=path flags, checking that PARAM is a file
+// parameter and path a file.
+func fileFlags(params []gojenkins.ParameterDefinition, flags []string) map[string]string {
+	uploads := map[string]string{}
+	for _, flag := range flags {
+		kv := strings.SplitN(flag, "=", 2)
+		if len(kv) != 2 {
+			log.Fatalf("--file %s is not PARAM=path", flag)
+		}
+		param, ok := pkg.FindParameter(params, kv[0])
+		if !ok || param.Type != pkg.FileParameter {
+			log.Fatalf("%s is not a file parameter of this job", kv[0])
+		}
+		if _, err := pkg.CheckParameter(param, kv[1]); err != nil {
+			log.Fatal(err)
+		}
+		uploads[kv[0]] = kv[1]
+	}
+	return uploads
 }
 
-func printShortCut(job string, choices map[string]string) string {
+// askParameter prompts for a single parameter in the way that suits its type.
+func askParameter(param gojenkins.ParameterDefinition, defaultValue string) string {
+	message := fmt.Sprintf("%s: ", param.Name)
+	validate := survey.WithValidator(func(answer interface{}) error {
+		_, err := pkg.CheckParameter(param, fmt.Sprintf("%v", answer))
+		return err
+	})
+
+	result := ""
+	switch {
+	case param.Type == pkg.BooleanParameter:
+		confirmed, _ := strconv.ParseBool(defaultValue)
+		survey.AskOne(&survey.Confirm{
+			Message: message,
+			Help:    param.Description,
+			Default: confirmed,
+		}, &confirmed)
+		result = strconv.FormatBool(confirmed)
+	case len(param.Choices) > 0:
+		prompt := &survey.Select{
+			Message: message,
+			Help:    param.Description,
+			Options: param.Choices,
+		}
+		for _, choice := range param.Choices {
+			if choice == defaultValue {
+				prompt.Default = defaultValue
+			}
+		}
+		survey.AskOne(prompt, &result)
+	case param.Type == pkg.TextParameter:
+		survey.AskOne(&survey.Editor{
+			Message:       message,
+			Help:          param.Description,
+			Default:       defaultValue,
+			AppendDefault: true,
+			HideDefault:   true,
+		}, &result)
+	case param.Type == pkg.PasswordParameter:
+		survey.AskOne(&survey.Password{
+			Message: message,
+			Help:    param.Description,
+		}, &result)
+		if result == "" {
+			result = defaultValue
+		}
+	case param.Type == pkg.FileParameter:
+		survey.AskOne(&survey.Input{
+			Message: fmt.Sprintf("%s (path to a file): ", param.Name),
+			Help:    param.Description,
+			Default: defaultValue,
+		}, &result, validate)
+	default:
+		survey.AskOne(&survey.Input{
+			Message: message,
+			Help:    param.Description,
+			Default: defaultValue,
+		}, &result, validate)
+	}
+	return result
+}
+
+// displayValue hides password values and shortens multi-line text.
+func displayValue(param gojenkins.ParameterDefinition, value string) string {
+	switch {
+	case value == "":
+		return "None"
+	case param.Type == pkg.PasswordParameter:
+		return "********"
+	case strings.Contains(value, "\n"):
+		lines := strings.Count(value, "\n") + 1
+		return fmt.Sprintf("%s... (%d lines)", strings.SplitN(value, "\n", 2)[0], lines)
+	}
+	return value
+}
+
+// confirmParameters shows the picked parameters and waits for [Enter].
+func confirmParameters(thejob *gojenkins.Job, jobPath string, params []gojenkins.ParameterDefinition, choices map[string]string) {
+	tm.Clear()
+	tm.Flush()
+	paramTable := table.NewWriter()
+	//paramTable.SetOutputMirror(os.Stdout)
+	paramTable.AppendHeader(table.Row{"PARAMETER", "CHOICE"})
+	for _, param := range params {
+		paramTable.AppendRow(table.Row{param.Name, displayValue(param, choices[param.Name])})
+	}
+	output := paramTable.Render()
+	shortcut := printShortCut(jobPath, params, choices)
+
+	fmt.Println(shortcut)
+	fmt.Println("----")
+	// Add some content to the box
+	// Note that you can add ANY content, even tables
+	job := strings.Split(thejob.Raw.FullName, "/")[:len(strings.Split(thejob.Raw.FullName, "/"))-1]
+	branch := strings.Split(thejob.Raw.FullName, "/")[len(strings.Split(thejob.Raw.FullName, "/"))-1]
+	fmt.Print("Job: ", Cyan(job), "\n")
+	fmt.Print("Branch: ", Cyan(branch), "\n")
+	fmt.Print(output)
+	fmt.Print("\nPlease confirm these parameters by pressing ", Yellow("[Enter]"), "\n")
+
+	// Move Box to approx center of the screen
+
+	fmt.Scanln()
+}
+
+// invokeAndReport runs the job, prints how the build went and exits with a
+// non-zero status unless it succeeded.
+func invokeAndReport(client pkg.Client, thejob *gojenkins.Job, choices map[string]string, opts followOptions) {
+	ctx, cancel := interruptContext(opts.abortOnInterrupt)
+	defer cancel()
+	result, err := pkg.InvokeJobWith(ctx, client, thejob, choices, opts.follow(client))
+	if err != nil && ctx.Err() != nil {
+		if result == nil {
+			// Still queued, StartJob took it off the queue
+			fmt.Println("Cancelled the queued build")
+			os.Exit(130)
+		}
+		build, buildErr := thejob.GetBuild(context.Background(), result.Number)
+		if buildErr != nil {
+			log.Fatal(buildErr)
+		}
+		offerAbort(build)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if structuredOutput() {
+		printStructured(newBuildDocument(result))
+	} else {
+		printBuildResult(result)
+	}
+	if !result.Succeeded() {
+		os.Exit(1)
+	}
+}
+
+func printShortCut(job string, params []gojenkins.ParameterDefinition, choices map[string]string) string {
 	builderString := fmt.Sprintf("Next time, type this to run this build without a prompt!\n")
 	builderString += fmt.Sprintf("%s %s run %s --interactive=False -- ", Yellow("$"), Green("goose"), Cyan(job))
 
-	for arg, value := range choices {
-		if value != "" {
-			builderString += fmt.Sprintf("--%s='%s' ", arg, value)
+	for _, param := range params {
+		value := choices[param.Name]
+		// Passwords don't belong in shell history
+		if value != "" && param.Type != pkg.PasswordParameter {
+			builderString += fmt.Sprintf("--%s='%s' ", param.Name, value)
 		}
 	}
 
 	return builderString
 }
 
+func printBuildResult(result *pkg.BuildResult) {
+	status := Green(result.Result)
+	if !result.Succeeded() {
+		status = Red(result.Result)
+	}
+	fmt.Print("Build ", Cyan(result.Number), " finished with ", status, " in ", result.Duration.Round(time.Second), "\n")
+	fmt.Println(result.URL)
+}
+
 func init() {
 
 	rootCmd.AddCommand(runCmd)
 
 	runCmd.Flags().BoolP("interactive", "i", true, "Help message for toggle")
 	runCmd.Flags().StringP("branch", "b", "", "Help message for toggle")
+	addFollowFlags(runCmd)
+	runCmd.Flags().StringArray("file", nil, "upload a local file to a file parameter with PARAM=path (repeatable)")
+	runCmd.Flags().String("preset", "", "start from the parameters saved in this preset")
+	runCmd.Flags().String("save-preset", "", "save the chosen parameters as a preset with this name")
 }
 
 func getJenkinsJob(args []string) []string {
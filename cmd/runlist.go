@@ -24,6 +24,11 @@ SOFTWARE.
 package cmd
 
 import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
 	"github.com/rallyhealth/goose/pkg"
 	"github.com/spf13/cobra"
 )
@@ -50,17 +55,139 @@ goose runlist myBatchFile
 """
 
 Will run 2 jobs concurrently for the 'Path/To/My/Job' on the main branch. The 'VariableA' and 'VariableB' variables will be the same for these 4 job runs. The end result is that each job is run with the same variables with 'VariableC' being different for each run.
+
+Substitute lists are paired up by index. Set 'Mode: matrix' to run every combination instead:
+
+"""
+BatchJobs:
+  - Job: Path/To/My/Job
+    Mode: matrix
+    Substitute:
+      tenant: [a,b]
+      region: [x,y]
+    Exclude:
+      - tenant: b
+        region: y
+    Include:
+      - tenant: c
+        region: z
+"""
+
+Will run a/x, a/y, b/x and c/z. Exclude removes matching combinations and Include either adds keys to
+the combinations it matches or adds a new run.
+
+File parameters are uploaded from the local files listed under 'Files', the same file for every run:
+
+"""
+BatchJobs:
+  - Job: Path/To/My/Job
+    Files:
+      manifest: ./manifest.yaml
+"""
+
+Batch jobs can also be grouped into named stages. A stage starts as soon as every stage in its
+'DependsOn' list has succeeded, and is skipped when one of them fails:
+
+"""
+Stages:
+  - Name: build
+    BatchJobs:
+      - Job: Path/To/Build
+  - Name: deploy
+    DependsOn: [build]
+    BatchJobs:
+      - Job: Path/To/Deploy
+        Substitute:
+          tenant: [a,b]
+"""
+
+By default every run of a batch job starts at once. Limit that and decide what happens on failure
+in the batch file, either at the top level or on a single BatchJob:
+
+"""
+MaxParallel: 5          # at most 5 builds at once
+FailFast: true          # stop starting new runs once one fails
+AbortRunning: true      # and abort the builds still running
+ContinueOnError: false  # move on to the next BatchJob after a failure
+"""
+
+The --max-parallel, --fail-fast, --abort-running and --continue-on-error flags override the top level settings.
+
+The logs of builds running at the same time are printed line by line, each line tagged with the job and
+substitute values it belongs to. Set 'LogDir: some/dir' or pass --log-dir to write every build's full log
+to its own file instead.
+
+Pass --dashboard to replace the logs with a table of every run that refreshes each second, showing its
+status, build number, elapsed time and last log line. A summary is printed at the end and goose exits
+with a non-zero status if any run failed.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Run each command async OR sequentially
-		jobList := pkg.ReadBatchJobFile(args[0])
-		pkg.RunJobList(jobList, Jenky)
+		jobList, err := pkg.ReadBatchJobFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if cmd.Flags().Changed("log-dir") {
+			jobList.LogDir, _ = cmd.Flags().GetString("log-dir")
+		}
+		if cmd.Flags().Changed("max-parallel") {
+			jobList.MaxParallel, _ = cmd.Flags().GetInt("max-parallel")
+		}
+		if cmd.Flags().Changed("fail-fast") {
+			failFast, _ := cmd.Flags().GetBool("fail-fast")
+			jobList.FailFast = &failFast
+		}
+		if cmd.Flags().Changed("abort-running") {
+			abortRunning, _ := cmd.Flags().GetBool("abort-running")
+			jobList.AbortRunning = &abortRunning
+		}
+		if cmd.Flags().Changed("continue-on-error") {
+			continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+			jobList.ContinueOnError = &continueOnError
+		}
+		dashboard, _ := cmd.Flags().GetBool("dashboard")
+		if !dashboard {
+			if err := pkg.RunJobList(jobList, jenkins(cmd)); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		jobList.Status = pkg.NewBatchStatus()
+		// Stage progress would break the redraw, it's shown after the summary.
+		var stageOutput bytes.Buffer
+		jobList.Out = &stageOutput
+		done := make(chan struct{})
+		drawn := make(chan struct{})
+		go func() {
+			showDashboard(jobList.Status, done)
+			close(drawn)
+		}()
+		err = pkg.RunJobList(jobList, jenkins(cmd))
+		close(done)
+		<-drawn
+
+		printSummary(jobList.Status.Snapshot())
+		fmt.Print(stageOutput.String())
+		if err != nil {
+			fmt.Println(err)
+		}
+		if err != nil || jobList.Status.Failed() {
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(runlistCmd)
 
+	runlistCmd.Flags().Int("max-parallel", 0, "maximum number of builds running at once (0 is unlimited)")
+	runlistCmd.Flags().Bool("fail-fast", false, "stop starting new runs after the first failure")
+	runlistCmd.Flags().Bool("abort-running", false, "abort running builds when fail-fast trips")
+	runlistCmd.Flags().Bool("continue-on-error", false, "keep running the next batch job after a failure")
+	runlistCmd.Flags().String("log-dir", "", "write each build's full log to its own file in this directory")
+	runlistCmd.Flags().Bool("dashboard", false, "show a live table of every run instead of the build logs")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
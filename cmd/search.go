@@ -41,20 +41,30 @@ var searchCmd = &cobra.Command{
 
 	The format is github.com/[your argument].git
 
-	Putting no argument will search your current working directory`,
+	Putting no argument will search your current working directory
+
+	With --output json or yaml the jobs are keyed by the git repo they build.`,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		if rebuildIndex == true {
 			fmt.Println("Rebuilding job index...")
-			pkg.BuildJobIndex(Jenky)
+			pkg.BuildJobIndex(jenkins(cmd))
 		}
 
 		if len(args) == 0 {
+			if structuredOutput() {
+				printStructured(SearchDocument{pkg.GetCurrentGitRepo(): pkg.FindCurrentRepoJobs()})
+				return
+			}
 			fmt.Println("Jobs Associated with ", Cyan("current directory"))
 			fmt.Println(pkg.FindCurrentRepoJobs())
 		}
 		if len(args) == 1 {
-			repo := fmt.Sprintf("github.com/%s.git", args[1])
+			repo := fmt.Sprintf("github.com/%s.git", args[0])
+			if structuredOutput() {
+				printStructured(SearchDocument{repo: pkg.GetAffiliatedJobs(repo)})
+				return
+			}
 			fmt.Println("Jobs Associated with : ", Cyan(repo))
 			fmt.Println(pkg.GetAffiliatedJobs(repo))
 		}
@@ -0,0 +1,150 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	tm "github.com/buger/goterm"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/kireledan/gojenkins"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// stagesCmd represents the stages command
+var stagesCmd = &cobra.Command{
+	Use:   "stages [job] [build#]",
+	Short: "Show the stages of a pipeline build",
+	Long: `stages shows the stages and parallel branches of a pipeline build with their status and duration.
+	While the build runs the view refreshes until it finishes.
+
+	Use --log <stage> to print the console output of a single stage or branch, e.g. the one that failed.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, build := locateBuild(jenkins(cmd), args)
+		stage, _ := cmd.Flags().GetString("log")
+		if stage != "" {
+			pipeline, err := pkg.GetPipeline(context.TODO(), build)
+			if err != nil {
+				log.Fatal(err)
+			}
+			output, err := pkg.GetStageLog(context.TODO(), build, pipeline, stage)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(output)
+			return
+		}
+
+		if structuredOutput() {
+			pipeline, err := pkg.GetPipeline(context.TODO(), build)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printStructured(pipeline)
+			return
+		}
+		fmt.Println(build.GetUrl())
+		if err := followStages(context.TODO(), build, nil); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// followStages redraws the stages of a build every two seconds until it
+// stops, then prints them one last time. Input steps are handed to handle
+// when it is set.
+func followStages(ctx context.Context, B *gojenkins.Build, handle pkg.InputHandler) error {
+	handled := map[string]bool{}
+	for {
+		if _, err := B.Poll(ctx); err != nil {
+			return err
+		}
+		if handle != nil && B.Raw.Building {
+			if err := pkg.HandleInputs(ctx, B, handled, handle); err != nil {
+				return err
+			}
+		}
+		pipeline, err := pkg.GetPipeline(ctx, B)
+		if err != nil {
+			return err
+		}
+		if !B.Raw.Building {
+			fmt.Println(renderStages(pipeline))
+			return nil
+		}
+		tm.Clear()
+		tm.MoveCursor(1, 1)
+		tm.Println(B.GetUrl())
+		tm.Println(renderStages(pipeline))
+		tm.Flush()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func renderStages(pipeline *pkg.Pipeline) string {
+	stages := table.NewWriter()
+	stages.AppendHeader(table.Row{"STAGE", "STATUS", "DURATION"})
+	for _, stage := range pipeline.Stages {
+		stages.AppendRow(table.Row{stage.Name, colorStageStatus(stage.Status), stage.Duration().Round(time.Second)})
+		for i, branch := range stage.Branches {
+			prefix := "├─ "
+			if i == len(stage.Branches)-1 {
+				prefix = "└─ "
+			}
+			stages.AppendRow(table.Row{prefix + branch.Name, colorStageStatus(branch.Status), branch.Duration().Round(time.Second)})
+		}
+	}
+	return stages.Render()
+}
+
+func colorStageStatus(status string) Value {
+	switch status {
+	case pkg.PipelineSuccess:
+		return Green(status)
+	case pkg.PipelineFailed:
+		return Red(status)
+	case pkg.PipelineInProgress:
+		return Cyan(status)
+	case pkg.PipelineUnstable, pkg.PipelineAborted, pkg.PipelinePaused:
+		return Yellow(status)
+	default:
+		return White(status)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(stagesCmd)
+
+	stagesCmd.Flags().String("log", "", "print the console output of this stage or branch")
+}
@@ -0,0 +1,116 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kireledan/gojenkins"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+var stopGrace time.Duration
+
+// stopCmd represents the stop command
+var stopCmd = &cobra.Command{
+	Use:   "stop [job] [build#]",
+	Short: "Abort a running jenkins build",
+	Long: `stop aborts a build on jenkins. Without a build number the last build of the job is stopped,
+	and without a job it is located from the current git repo and branch like run does.
+
+	If the build is still running after --grace, goose escalates to term and then to kill.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, build := locateBuild(jenkins(cmd), args)
+		if !build.IsRunning(context.TODO()) {
+			fmt.Print("Build ", Cyan(build.GetBuildNumber()), " is not running, it ended with ", colorResult(build.GetResult()), "\n")
+			return
+		}
+		stopAndReport(build)
+	},
+}
+
+// stopAndReport aborts a build and reports how it ended.
+func stopAndReport(build *gojenkins.Build) {
+	result, err := pkg.StopBuild(context.Background(), build, stopGrace, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if structuredOutput() {
+		printStructured(newBuildDocument(result))
+	} else {
+		printBuildResult(result)
+	}
+}
+
+// interruptContext returns a context that the first Ctrl-C cancels when
+// enabled, so goose can offer to abort the build it follows instead of
+// just exiting.
+func interruptContext(enabled bool) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !enabled {
+		return ctx, cancel
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// offerAbort asks whether a build goose was interrupted while following
+// should be aborted on jenkins, then exits.
+func offerAbort(build *gojenkins.Build) {
+	fmt.Println()
+	abort := false
+	survey.AskOne(&survey.Confirm{
+		Message: fmt.Sprintf("Abort build %d on jenkins?", build.GetBuildNumber()),
+	}, &abort)
+	if !abort {
+		fmt.Print("Build ", Cyan(build.GetBuildNumber()), " keeps running: ", build.GetUrl(), "\n")
+		os.Exit(130)
+	}
+	stopAndReport(build)
+	os.Exit(130)
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+
+	stopCmd.Flags().DurationVar(&stopGrace, "grace", 10*time.Second, "how long to wait for the build to stop before escalating")
+}
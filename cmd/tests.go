@@ -0,0 +1,136 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/rallyhealth/goose/pkg"
+	"github.com/spf13/cobra"
+)
+
+// Lines of a stack trace printed unless --full is given.
+const shortStackTrace = 10
+
+// TestsDocument is printed by `goose tests` with --output json or yaml.
+// Comparison is left out when the previous build has no test report.
+type TestsDocument struct {
+	Report     *pkg.TestReport     `json:"report" yaml:"report"`
+	Comparison *pkg.TestComparison `json:"comparison,omitempty" yaml:"comparison,omitempty"`
+}
+
+// testsCmd represents the tests command
+var testsCmd = &cobra.Command{
+	Use:   "tests [job] [build#]",
+	Short: "Summarize the test results of a build",
+	Long: `tests prints the pass, fail and skip counts of a build's JUnit report, followed by every failing test
+	with its error message and stack trace.
+
+	The failures are compared with the build before it, so new failures stand out from tests that were already failing.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, build := locateBuild(jenkins(cmd), args)
+		report, err := pkg.GetTestReport(context.TODO(), build)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var comparison *pkg.TestComparison
+		if previous, err := pkg.PreviousBuild(context.TODO(), build); err == nil {
+			if previousReport, err := pkg.GetTestReport(context.TODO(), previous); err == nil {
+				c := pkg.CompareTestReports(report, previousReport)
+				comparison = &c
+			}
+		}
+
+		if structuredOutput() {
+			printStructured(TestsDocument{Report: report, Comparison: comparison})
+			return
+		}
+		full, _ := cmd.Flags().GetBool("full")
+		fmt.Println(build.GetUrl())
+		printTestReport(report, comparison, full)
+	},
+}
+
+func printTestReport(report *pkg.TestReport, comparison *pkg.TestComparison, full bool) {
+	counts := table.NewWriter()
+	counts.AppendHeader(table.Row{"PASSED", "FAILED", "SKIPPED", "DURATION"})
+	counts.AppendRow(table.Row{Green(report.Passed), Red(report.Failed), Yellow(report.Skipped), fmt.Sprintf("%.1fs", report.DurationSeconds)})
+	fmt.Println(counts.Render())
+
+	newFailures := map[string]bool{}
+	if comparison != nil {
+		for _, name := range comparison.NewFailures {
+			newFailures[name] = true
+		}
+	}
+	for _, failure := range report.Failures {
+		fmt.Println()
+		label := Red("FAILED")
+		if comparison != nil && !newFailures[failure.FullName()] {
+			label = Yellow("STILL FAILING")
+		} else if comparison != nil {
+			label = Red("NEW FAILURE")
+		}
+		fmt.Println(label, Bold(failure.FullName()))
+		if failure.ErrorDetails != "" {
+			fmt.Println(failure.ErrorDetails)
+		}
+		if failure.ErrorStackTrace != "" {
+			fmt.Println(Faint(stackTrace(failure.ErrorStackTrace, full)))
+		}
+	}
+
+	if comparison == nil {
+		return
+	}
+	fmt.Println()
+	fmt.Printf("Compared with build %d: %d new failures, %d still failing, %d fixed\n",
+		comparison.Previous, len(comparison.NewFailures), len(comparison.StillFailing), len(comparison.Fixed))
+	for _, name := range comparison.Fixed {
+		fmt.Println(Green("FIXED"), name)
+	}
+}
+
+// stackTrace cuts a stack trace down to its first lines unless full is set.
+func stackTrace(trace string, full bool) string {
+	trace = strings.TrimRight(trace, "\n")
+	lines := strings.Split(trace, "\n")
+	if full || len(lines) <= shortStackTrace {
+		return trace
+	}
+	return strings.Join(lines[:shortStackTrace], "\n") + fmt.Sprintf("\n\t... %d more lines (--full to show them)", len(lines)-shortStackTrace)
+}
+
+func init() {
+	rootCmd.AddCommand(testsCmd)
+
+	testsCmd.Flags().Bool("full", false, "print whole stack traces")
+}
@@ -27,7 +27,7 @@ require (
 	github.com/pelletier/go-toml v1.8.1 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/prologic/bitcask v0.3.10
-	github.com/rafaeldias/async v1.0.0
+	github.com/sergi/go-diff v1.1.0
 	github.com/spf13/afero v1.4.0 // indirect
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/viper v1.7.1
@@ -0,0 +1,230 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// BuildArtifact is a file archived by a build. Size is -1 when jenkins
+// didn't say how large it is.
+type BuildArtifact struct {
+	Name string `json:"name" yaml:"name"`
+	Path string `json:"path" yaml:"path"`
+	URL  string `json:"url" yaml:"url"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// DownloadedArtifact tells where an artifact was saved, or why it wasn't.
+type DownloadedArtifact struct {
+	Artifact BuildArtifact `json:"artifact" yaml:"artifact"`
+	File     string        `json:"file,omitempty" yaml:"file,omitempty"`
+	Err      error         `json:"-" yaml:"-"`
+	Error    string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ListArtifacts returns the artifacts of a build with their sizes. The
+// build's api doesn't include sizes, so each artifact is asked for with a
+// HEAD request, at most concurrencyLimit at a time.
+func ListArtifacts(ctx context.Context, B *gojenkins.Build, concurrencyLimit int) ([]BuildArtifact, error) {
+	artifacts := make([]BuildArtifact, len(B.Raw.Artifacts))
+	for i, artifact := range B.Raw.Artifacts {
+		artifacts[i] = BuildArtifact{
+			Name: artifact.FileName,
+			Path: artifact.RelativePath,
+			URL:  strings.TrimSuffix(B.GetUrl(), "/") + "/artifact/" + escapeArtifactPath(artifact.RelativePath),
+			Size: -1,
+		}
+	}
+	errs := boundedParallel(len(artifacts), concurrencyLimit, func(i int) error {
+		resp, err := artifactRequest(ctx, B, http.MethodHead, artifacts[i].Path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return errors.Errorf("could not get %s: %s", artifacts[i].Path, resp.Status)
+		}
+		artifacts[i].Size = resp.ContentLength
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			return artifacts, err
+		}
+	}
+	return artifacts, nil
+}
+
+// FilterArtifacts keeps the artifacts whose path or file name matches one
+// of the glob patterns. Every artifact matches when there are no patterns.
+func FilterArtifacts(artifacts []BuildArtifact, patterns []string) ([]BuildArtifact, error) {
+	if len(patterns) == 0 {
+		return artifacts, nil
+	}
+	matching := []BuildArtifact{}
+	for _, artifact := range artifacts {
+		for _, pattern := range patterns {
+			byPath, err := path.Match(pattern, artifact.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "bad pattern %s", pattern)
+			}
+			byName, _ := path.Match(pattern, artifact.Name)
+			if byPath || byName {
+				matching = append(matching, artifact)
+				break
+			}
+		}
+	}
+	return matching, nil
+}
+
+// DownloadArtifacts saves artifacts under dir, keeping their paths, with at
+// most concurrencyLimit downloads at a time. The results are in the order
+// of artifacts.
+func DownloadArtifacts(ctx context.Context, B *gojenkins.Build, artifacts []BuildArtifact, dir string, concurrencyLimit int) []DownloadedArtifact {
+	downloads := make([]DownloadedArtifact, len(artifacts))
+	errs := boundedParallel(len(artifacts), concurrencyLimit, func(i int) error {
+		downloads[i].Artifact = artifacts[i]
+		file, err := artifactFile(dir, artifacts[i].Path)
+		if err != nil {
+			return err
+		}
+		downloads[i].File = file
+		return downloadArtifact(ctx, B, artifacts[i].Path, file)
+	})
+	for i, err := range errs {
+		if err != nil {
+			downloads[i].Err = err
+			downloads[i].Error = err.Error()
+		}
+	}
+	return downloads
+}
+
+// artifactFile is where an artifact is saved under dir. Paths leaving dir
+// are refused.
+func artifactFile(dir string, relativePath string) (string, error) {
+	file := filepath.Join(dir, filepath.FromSlash(relativePath))
+	if rel, err := filepath.Rel(dir, file); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("refusing to save %s outside of %s", relativePath, dir)
+	}
+	return file, nil
+}
+
+func downloadArtifact(ctx context.Context, B *gojenkins.Build, relativePath string, file string) error {
+	resp, err := artifactRequest(ctx, B, http.MethodGet, relativePath)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("could not download %s: %s", relativePath, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return errors.Wrapf(err, "could not download %s", relativePath)
+	}
+	return out.Close()
+}
+
+// artifactRequest sends a request for an artifact of B. Artifacts can be
+// large, so unlike the gojenkins requester the body isn't read into memory
+// and only ctx limits how long the download takes.
+func artifactRequest(ctx context.Context, B *gojenkins.Build, method string, relativePath string) (*http.Response, error) {
+	requester := B.Jenkins.Requester
+	req, err := http.NewRequest(method, requester.Base+B.Base+"/artifact/"+escapeArtifactPath(relativePath), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if requester.BasicAuth != nil {
+		req.SetBasicAuth(requester.BasicAuth.Username, requester.BasicAuth.Password)
+	}
+	return transferClient(requester).Do(req)
+}
+
+func escapeArtifactPath(relativePath string) string {
+	segments := strings.Split(relativePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// boundedParallel calls work for 0..count-1 in parallel, with at most
+// concurrencyLimit calls running at a time, and returns their errors by
+// index.
+func boundedParallel(count int, concurrencyLimit int, work func(i int) error) []error {
+	if concurrencyLimit < 1 {
+		concurrencyLimit = 1
+	}
+	type done struct {
+		index int
+		err   error
+	}
+
+	// this buffered channel will block at the concurrency limit
+	semaphoreChan := make(chan struct{}, concurrencyLimit)
+	resultsChan := make(chan done)
+	defer func() {
+		close(semaphoreChan)
+		close(resultsChan)
+	}()
+
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			semaphoreChan <- struct{}{}
+			err := work(i)
+			<-semaphoreChan
+			resultsChan <- done{i, err}
+		}(i)
+	}
+
+	errs := make([]error, count)
+	for i := 0; i < count; i++ {
+		result := <-resultsChan
+		errs[result.index] = result.err
+	}
+	return errs
+}
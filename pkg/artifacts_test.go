@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg/fakejenkins"
+)
+
+func artifactServer() *httptest.Server {
+	files := map[string]string{
+		"/job/Example/1/artifact/target/app.jar":            "jar contents",
+		"/job/Example/1/artifact/reports/unit%20tests.html": "<html></html>",
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+}
+
+func artifactBuild(server *httptest.Server) *gojenkins.Build {
+	jenkins := gojenkins.CreateJenkins(server.Client(), server.URL)
+	return &gojenkins.Build{Jenkins: jenkins, Base: "/job/Example/1", Raw: &gojenkins.BuildResponse{
+		URL: server.URL + "/job/Example/1/",
+		Artifacts: []struct {
+			DisplayPath  string `json:"displayPath"`
+			FileName     string `json:"fileName"`
+			RelativePath string `json:"relativePath"`
+		}{
+			{FileName: "app.jar", RelativePath: "target/app.jar"},
+			{FileName: "unit tests.html", RelativePath: "reports/unit tests.html"},
+		},
+	}}
+}
+
+func TestListArtifacts(t *testing.T) {
+	server := artifactServer()
+	defer server.Close()
+
+	artifacts, err := ListArtifacts(context.Background(), artifactBuild(server), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BuildArtifact{
+		{Name: "app.jar", Path: "target/app.jar", URL: server.URL + "/job/Example/1/artifact/target/app.jar", Size: 12},
+		{Name: "unit tests.html", Path: "reports/unit tests.html", URL: server.URL + "/job/Example/1/artifact/reports/unit%20tests.html", Size: 13},
+	}
+	if !reflect.DeepEqual(artifacts, want) {
+		t.Errorf("ListArtifacts() = %+v, want %+v", artifacts, want)
+	}
+}
+
+func TestFilterArtifacts(t *testing.T) {
+	artifacts := []BuildArtifact{
+		{Name: "app.jar", Path: "target/app.jar"},
+		{Name: "unit.html", Path: "reports/unit.html"},
+		{Name: "manifest.yaml", Path: "manifest.yaml"},
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{name: "no patterns", patterns: nil, want: []string{"target/app.jar", "reports/unit.html", "manifest.yaml"}},
+		{name: "file name", patterns: []string{"*.jar"}, want: []string{"target/app.jar"}},
+		{name: "path", patterns: []string{"reports/*", "*.yaml"}, want: []string{"reports/unit.html", "manifest.yaml"}},
+		{name: "nothing", patterns: []string{"*.zip"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matching, err := FilterArtifacts(artifacts, tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, artifact := range matching {
+				got = append(got, artifact.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterArtifacts(%v) = %v, want %v", tt.patterns, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FilterArtifacts(artifacts, []string{"["}); err == nil {
+		t.Error("FilterArtifacts() with a bad pattern should fail")
+	}
+}
+
+func TestDownloadArtifacts(t *testing.T) {
+	server := artifactServer()
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "goose-artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifacts := []BuildArtifact{
+		{Name: "app.jar", Path: "target/app.jar"},
+		{Name: "unit tests.html", Path: "reports/unit tests.html"},
+		{Name: "gone.txt", Path: "gone.txt"},
+		{Name: "passwd", Path: "../passwd"},
+	}
+	downloads := DownloadArtifacts(context.Background(), artifactBuild(server), artifacts, dir, 2)
+	if len(downloads) != len(artifacts) {
+		t.Fatalf("DownloadArtifacts() = %+v", downloads)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "target", "app.jar"))
+	if err != nil || string(content) != "jar contents" || downloads[0].Err != nil {
+		t.Errorf("app.jar = %q, %v, %v", content, err, downloads[0].Err)
+	}
+	if downloads[1].File != filepath.Join(dir, "reports", "unit tests.html") || downloads[1].Err != nil {
+		t.Errorf("DownloadArtifacts() = %+v", downloads[1])
+	}
+	if downloads[2].Err == nil || downloads[3].Err == nil {
+		t.Errorf("DownloadArtifacts() of a missing artifact and one outside dir should fail: %+v", downloads[2:])
+	}
+}
+
+func TestBoundedParallel(t *testing.T) {
+	running := make(chan struct{}, 2)
+	errs := boundedParallel(5, 2, func(i int) error {
+		select {
+		case running <- struct{}{}:
+		default:
+			t.Error("more calls running than the limit")
+		}
+		defer func() { <-running }()
+		if i == 3 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if (err != nil) != (i == 3) {
+			t.Errorf("boundedParallel() error %d = %v", i, err)
+		}
+	}
+}
+
+func TestDownloadSlowArtifact(t *testing.T) {
+	server := fakejenkins.New(&fakejenkins.Team{Jobs: []*fakejenkins.Job{{Name: "example", Builds: []*fakejenkins.Build{
+		{Number: 1, Result: "SUCCESS", Artifacts: map[string]string{"target/app.jar": "jar contents"}, ArtifactDelay: 500 * time.Millisecond},
+	}}}})
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "goose-artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The api client gives up long before the download is done
+	ctx := context.Background()
+	J, err := gojenkins.CreateJenkins(&http.Client{Timeout: 200 * time.Millisecond}, server.URL, fakejenkins.User, fakejenkins.Token).Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := J.GetJob(ctx, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	build, err := job.GetBuild(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts, err := ListArtifacts(ctx, build, 1)
+	if err != nil || len(artifacts) != 1 || artifacts[0].Size != 12 {
+		t.Fatalf("ListArtifacts() = %+v, %v", artifacts, err)
+	}
+
+	downloads := DownloadArtifacts(ctx, build, artifacts, dir, 1)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "target", "app.jar"))
+	if downloads[0].Err != nil || err != nil || string(content) != "jar contents" {
+		t.Errorf("app.jar = %q, %v, %v", content, err, downloads[0].Err)
+	}
+
+	// Cancelling the context still stops the download
+	cancelled, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	downloads = DownloadArtifacts(cancelled, build, artifacts, dir, 1)
+	if downloads[0].Err == nil {
+		t.Error("DownloadArtifacts() with a cancelled context should fail")
+	}
+}
@@ -25,64 +25,240 @@ SOFTWARE.
 package pkg
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/kireledan/gojenkins"
-	"github.com/rafaeldias/async"
+	color "github.com/logrusorgru/aurora/v3"
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	// ModeZip pairs substitute values by index. This is the default.
+	ModeZip = "zip"
+	// ModeMatrix runs every combination of the substitute values.
+	ModeMatrix = "matrix"
+)
+
+// ErrCancelled is returned for runs that never started because the batch was
+// stopped by its FailFast policy.
+var ErrCancelled = errors.New("cancelled")
+
+// RunPolicy controls how many runs of a batch job are in flight at once and
+// what happens when one of them fails. It can be set for the whole batch file
+// and overridden per BatchJob.
+type RunPolicy struct {
+	// MaxParallel caps the number of builds running at once. 0 means no limit.
+	MaxParallel int `yaml:"MaxParallel,omitempty"`
+	// FailFast stops starting new runs of a batch job once one of them fails.
+	FailFast *bool `yaml:"FailFast,omitempty"`
+	// ContinueOnError keeps going with the next batch job after one fails.
+	ContinueOnError *bool `yaml:"ContinueOnError,omitempty"`
+	// AbortRunning aborts builds that are still running when FailFast trips.
+	AbortRunning *bool `yaml:"AbortRunning,omitempty"`
+}
+
+// Merge returns p with every setting that is set in override replaced.
+func (p RunPolicy) Merge(override RunPolicy) RunPolicy {
+	if override.MaxParallel != 0 {
+		p.MaxParallel = override.MaxParallel
+	}
+	if override.FailFast != nil {
+		p.FailFast = override.FailFast
+	}
+	if override.ContinueOnError != nil {
+		p.ContinueOnError = override.ContinueOnError
+	}
+	if override.AbortRunning != nil {
+		p.AbortRunning = override.AbortRunning
+	}
+	return p
+}
+
+func (p RunPolicy) failFast() bool {
+	return p.FailFast != nil && *p.FailFast
+}
+
+func (p RunPolicy) continueOnError() bool {
+	return p.ContinueOnError != nil && *p.ContinueOnError
+}
+
+func (p RunPolicy) abortRunning() bool {
+	return p.AbortRunning != nil && *p.AbortRunning
+}
+
+func (p RunPolicy) parallelism(runs int) int {
+	if p.MaxParallel > 0 && p.MaxParallel < runs {
+		return p.MaxParallel
+	}
+	if runs == 0 {
+		return 1
+	}
+	return runs
+}
+
 type BatchJob struct {
 	Job        string              `yaml:"Job" `
+	Mode       string              `yaml:"Mode,omitempty"`
 	Variables  map[string]string   `yaml:"Variables" `
 	Substitute map[string][]string `yaml:"Substitute"`
+	Exclude    []map[string]string `yaml:"Exclude,omitempty"`
+	Include    []map[string]string `yaml:"Include,omitempty"`
+	// Files maps file parameters to the local file uploaded for every run.
+	Files     map[string]string `yaml:"Files,omitempty"`
+	RunPolicy `yaml:",inline"`
 }
 
 type JobList struct {
-	Batch []BatchJob `yaml:"BatchJobs"`
+	Batch  []BatchJob `yaml:"BatchJobs"`
+	Stages []Stage    `yaml:"Stages,omitempty"`
+	// LogDir, when set, receives the full log of every build in its own file
+	// instead of the terminal.
+	LogDir    string `yaml:"LogDir,omitempty"`
+	RunPolicy `yaml:",inline"`
+	// Status, when set, is kept up to date with every run instead of
+	// printing the build logs.
+	Status *BatchStatus `yaml:"-"`
+	// Out receives the stage progress and report, stdout when nil.
+	Out io.Writer `yaml:"-"`
+}
+
+func (j JobList) out() io.Writer {
+	if j.Out == nil {
+		return os.Stdout
+	}
+	return j.Out
 }
 
-func ReadBatchJob(content []byte) JobList {
+// BatchOptions are the settings a single batch job runs with.
+type BatchOptions struct {
+	RunPolicy
+	LogDir string
+	Status *BatchStatus
+}
+
+func (j JobList) options(job BatchJob) BatchOptions {
+	return BatchOptions{
+		RunPolicy: j.RunPolicy.Merge(job.RunPolicy),
+		LogDir:    j.LogDir,
+		Status:    j.Status,
+	}
+}
+
+// ReadBatchJob parses a batch file and validates it, returning an error when
+// any of its jobs or stages is invalid.
+func ReadBatchJob(content []byte) (JobList, error) {
 	var config JobList
-	err := yaml.Unmarshal(content, &config)
-	check(err)
-	ValidateBatchJob(config)
-	return config
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return JobList{}, errors.Wrap(err, "could not parse the batch file")
+	}
+	if !ValidateBatchJob(config) {
+		return JobList{}, errors.New("the batch file is invalid")
+	}
+	return config, nil
 }
 
-func ReadBatchJobFile(filename string) JobList {
+func ReadBatchJobFile(filename string) (JobList, error) {
 	jobFileContent, err := ioutil.ReadFile(filename)
 	if err != nil {
-		panic(err)
+		return JobList{}, err
 	}
-	return ReadBatchJob(jobFileContent)
+	jobList, err := ReadBatchJob(jobFileContent)
+	return jobList, errors.Wrap(err, filename)
 }
 
+// ValidateBatchJob checks every batch job and reports how many runs each one
+// will create before anything is invoked.
 func ValidateBatchJob(jl JobList) bool {
-	for _, job := range jl.Batch {
-		if !validateSubstituteLength(job) {
-			return false
+	valid := validateBatchJobs(jl.Batch)
+	for _, stage := range jl.Stages {
+		if !validateBatchJobs(stage.Batch) {
+			valid = false
 		}
 	}
-	return true
+	if err := ValidateStages(jl.Stages); err != nil {
+		fmt.Println(err)
+		valid = false
+	}
+	return valid
+}
+
+func validateBatchJobs(batch []BatchJob) bool {
+	valid := true
+	for _, job := range batch {
+		switch job.Mode {
+		case "", ModeZip:
+			if len(job.Exclude) > 0 || len(job.Include) > 0 {
+				fmt.Printf("Job %s uses Exclude/Include which require 'Mode: %s'\n", job.Job, ModeMatrix)
+				valid = false
+				continue
+			}
+			if !validateSubstituteLength(job) {
+				valid = false
+				continue
+			}
+		case ModeMatrix:
+		default:
+			fmt.Printf("Job %s has unknown mode '%s'. Use '%s' or '%s'\n", job.Job, job.Mode, ModeZip, ModeMatrix)
+			valid = false
+			continue
+		}
+		for name, filename := range job.Files {
+			if info, err := os.Stat(filename); err != nil || info.IsDir() {
+				fmt.Printf("Job %s uploads %s as %s, but it is not a readable file\n", job.Job, filename, name)
+				valid = false
+			}
+		}
+		fmt.Printf("%s will create %d runs\n", job.Job, len(GenerateParameterList(job)))
+	}
+	return valid
 }
 
 func validateSubstituteLength(job BatchJob) bool {
-	substituteNumber := 0
+	substituteNumber := -1
 	for _, substitutes := range job.Substitute {
-		substituteNumber = len(substitutes)
-		if len(substitutes) > 0 {
-			if len(substitutes) != substituteNumber {
-				fmt.Println("Job substitutes must be of equal length")
-				return false
-			}
+		if substituteNumber == -1 {
+			substituteNumber = len(substitutes)
+		}
+		if len(substitutes) != substituteNumber {
+			fmt.Println("Job substitutes must be of equal length")
+			return false
 		}
 	}
 	return true
 }
 
+// GenerateParameterList expands a batch job into the parameters of every run.
 func GenerateParameterList(jobBatch BatchJob) []map[string]string {
+	var jobParameters []map[string]string
+	if jobBatch.Mode == ModeMatrix {
+		jobParameters = generateMatrix(jobBatch)
+	} else {
+		jobParameters = generateZip(jobBatch)
+		applyVariables(jobParameters, jobBatch.Variables)
+	}
+	// File parameters are given as the path of the file to upload
+	applyVariables(jobParameters, jobBatch.Files)
+	return jobParameters
+}
+
+func applyVariables(jobParameters []map[string]string, variables map[string]string) {
+	for batchIndex := range jobParameters {
+		for variable, value := range variables {
+			jobParameters[batchIndex][variable] = value
+		}
+	}
+}
+
+func generateZip(jobBatch BatchJob) []map[string]string {
 	// We iterate on each substitute
 	batchLength := 0
 	for _, substituteList := range jobBatch.Substitute {
@@ -99,53 +275,298 @@ func GenerateParameterList(jobBatch BatchJob) []map[string]string {
 			jobParameters[index][key] = substituteValue
 		}
 	}
-	for batchIndex, _ := range jobParameters {
-		for variable, value := range jobBatch.Variables {
-			jobParameters[batchIndex][variable] = value
+	return jobParameters
+}
+
+// generateMatrix builds the cartesian product of the substitutes, drops the
+// combinations matching an Exclude entry and then applies the Include
+// entries the same way CI matrices do: an entry that agrees with a
+// combination on every substitute key extends it, anything else becomes an
+// extra run. Include values win over Variables.
+func generateMatrix(jobBatch BatchJob) []map[string]string {
+	keys := make([]string, 0, len(jobBatch.Substitute))
+	for key := range jobBatch.Substitute {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	combinations := []map[string]string{}
+	if len(keys) > 0 {
+		combinations = append(combinations, map[string]string{})
+	}
+	for _, key := range keys {
+		var expanded []map[string]string
+		for _, combination := range combinations {
+			for _, value := range jobBatch.Substitute[key] {
+				next := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					next[k] = v
+				}
+				next[key] = value
+				expanded = append(expanded, next)
+			}
+		}
+		combinations = expanded
+	}
+
+	jobParameters := []map[string]string{}
+	for _, combination := range combinations {
+		excluded := false
+		for _, exclude := range jobBatch.Exclude {
+			if matchesEntry(combination, exclude) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			jobParameters = append(jobParameters, combination)
 		}
 	}
+	applyVariables(jobParameters, jobBatch.Variables)
 
+	for _, include := range jobBatch.Include {
+		extended := false
+		for _, combination := range jobParameters {
+			if matchesSubstitutes(combination, include, jobBatch.Substitute) {
+				for k, v := range include {
+					combination[k] = v
+				}
+				extended = true
+			}
+		}
+		if !extended {
+			extra := make(map[string]string, len(include)+len(jobBatch.Variables))
+			for k, v := range jobBatch.Variables {
+				extra[k] = v
+			}
+			for k, v := range include {
+				extra[k] = v
+			}
+			jobParameters = append(jobParameters, extra)
+		}
+	}
 	return jobParameters
 }
 
-func RunJobList(j JobList, Jenky *gojenkins.Jenkins) error {
+// matchesEntry reports whether every key of entry has the same value in params.
+func matchesEntry(params map[string]string, entry map[string]string) bool {
+	for k, v := range entry {
+		if params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSubstitutes reports whether entry agrees with params on every key
+// that is a substitute. Keys that aren't substitutes are free to be added.
+func matchesSubstitutes(params map[string]string, entry map[string]string, substitutes map[string][]string) bool {
+	for k, v := range entry {
+		if _, ok := substitutes[k]; ok && params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// RunJobList runs the top level batch jobs one after another and then the
+// stages in dependency order.
+func RunJobList(j JobList, client Client) error {
+	var failed []string
 	for _, job := range j.Batch {
-		err := InvokeBatchJob(Jenky, job)
+		opts := j.options(job)
+		err := InvokeBatchJob(client, job, opts)
+		if err != nil {
+			if !opts.continueOnError() {
+				return err
+			}
+			fmt.Fprintln(j.out(), color.Yellow("Continuing after failure of"), color.White(job.Job))
+			failed = append(failed, job.Job)
+		}
+	}
+	if len(j.Stages) > 0 {
+		results, err := RunStages(j, client)
+		if results != nil {
+			PrintStageReport(j.out(), results)
+		}
 		if err != nil {
 			return err
 		}
 	}
+	if len(failed) > 0 {
+		return errors.Errorf("batch jobs failed: %v", failed)
+	}
 	return nil
 }
 
-func InvokeBatchJob(Jenky *gojenkins.Jenkins, jobBatch BatchJob) error {
+// InvokeBatchJob runs every parameter set of a batch job, at most
+// MaxParallel at a time. When FailFast is set the first failure cancels the
+// runs that haven't started yet, and with AbortRunning the builds that are
+// still running are aborted too.
+//
+// The logs of the builds are interleaved line by line on stdout, each line
+// tagged with the run it belongs to. With a LogDir every build's log goes to
+// its own file instead and only progress is printed. With a Status nothing is
+// printed for the runs, their progress is tracked there instead.
+func InvokeBatchJob(client Client, jobBatch BatchJob, opts BatchOptions) error {
 	params := GenerateParameterList(jobBatch)
-	job, err := GetNestedJob(Jenky, jobBatch.Job)
+	job, err := client.GetJob(context.TODO(), jobBatch.Job)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	taskList := async.Tasks{}
 
-	for _, param := range params {
-		paramCopy := param
-		taskList = append(taskList, func() int {
-			InvokeJob(Jenky, job, paramCopy)
-			return 0
-		})
+	status := opts.Status
+	if status == nil {
+		status = NewBatchStatus()
 	}
+	tags := make([]string, len(params))
+	runs := make([]int, len(params))
+	width := 0
+	for i, param := range params {
+		tags[i] = BuildTag(jobBatch, param)
+		runs[i] = status.Add(tags[i])
+		if len(tags[i]) > width {
+			width = len(tags[i])
+		}
+	}
+	mux := NewLogMux(os.Stdout, width)
 
-	fmt.Println("running batch jobs..")
-	_, e := async.Concurrent(taskList)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	if e != nil {
-		return e
+	// this buffered channel will block at the concurrency limit
+	semaphoreChan := make(chan struct{}, opts.parallelism(len(params)))
+	errs := make([]error, len(params))
+	var wg sync.WaitGroup
+
+	if opts.Status == nil {
+		fmt.Println("running batch jobs..")
 	}
+	for i, param := range params {
+		select {
+		case semaphoreChan <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			errs[i] = ErrCancelled
+			status.Finish(runs[i], RunCancelled)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, param map[string]string) {
+			defer wg.Done()
+			defer func() { <-semaphoreChan }()
+
+			var out io.Writer
+			if opts.Status != nil {
+				out = status.Writer(runs[i])
+			} else {
+				w := mux.Writer(tags[i])
+				defer w.Close()
+				out = w
+			}
 
+			var logFile *os.File
+			if opts.LogDir != "" {
+				logFile, errs[i] = CreateLogFile(opts.LogDir, tags[i])
+				if errs[i] != nil {
+					status.Finish(runs[i], RunFailure)
+					return
+				}
+				defer logFile.Close()
+				fmt.Fprintln(out, "Logging to", logFile.Name())
+			}
+
+			errs[i] = runBatchEntry(ctx, client, jobCopy(job), param, opts.abortRunning(), out, logFile, func(build *gojenkins.Build) {
+				status.Start(runs[i], build.GetBuildNumber(), build.GetUrl())
+			})
+			switch errs[i] {
+			case nil:
+				status.Finish(runs[i], RunSuccess)
+			case ErrCancelled:
+				status.Finish(runs[i], RunCancelled)
+			default:
+				status.Finish(runs[i], RunFailure)
+				if opts.failFast() {
+					cancel()
+				}
+			}
+		}(i, param)
+	}
+	wg.Wait()
+
+	failures, cancelled := 0, 0
+	for i, e := range errs {
+		switch {
+		case e == ErrCancelled:
+			cancelled++
+		case e != nil:
+			failures++
+			if opts.Status == nil {
+				fmt.Println(color.Red("Run failed:"), tags[i], color.Red(e))
+			}
+		}
+	}
+	if failures > 0 || cancelled > 0 {
+		return errors.Errorf("%s: %d of %d runs failed, %d cancelled", jobBatch.Job, failures, len(params), cancelled)
+	}
 	return nil
 }
 
-func check(e error) {
-	if e != nil {
-		panic(e)
+// jobCopy gives a run a job of its own. gojenkins refreshes a job's Raw in
+// place whenever it polls it, e.g. on every invoke, so runs can't share one.
+func jobCopy(job *gojenkins.Job) *gojenkins.Job {
+	copied := *job
+	copied.Raw = new(gojenkins.JobResponse)
+	if data, err := json.Marshal(job.Raw); err == nil {
+		json.Unmarshal(data, copied.Raw)
+	}
+	return &copied
+}
+
+// runBatchEntry runs a single parameter set, writing progress to out and the
+// console to logFile, or to out when there is no logFile. started is called
+// once the build leaves the queue, and the build is saved to the history. If
+// ctx is cancelled before the build finishes it stops following it, and
+// aborts it when abort is set. Otherwise the build is left running and
+// recorded as detached.
+func runBatchEntry(ctx context.Context, client Client, job *gojenkins.Job, params map[string]string, abort bool, out io.Writer, logFile io.Writer, started func(*gojenkins.Build)) error {
+	build, err := StartJob(ctx, client, job, params, out)
+	if errors.Cause(err) == context.Canceled {
+		return ErrCancelled
+	}
+	if err != nil {
+		return err
+	}
+	started(build)
+	record := recordStart(job, params, build)
+	if logFile != nil {
+		err = SaveConsole(ctx, client, build, logFile)
+	} else {
+		err = FollowBuild(ctx, client, build, out)
 	}
+	var result *BuildResult
+	if err == nil {
+		result, err = WaitForResult(ctx, build)
+	}
+	if errors.Cause(err) == context.Canceled {
+		if abort {
+			fmt.Fprintln(out, color.Yellow("Aborting"), color.White(build.GetUrl()))
+			build.Stop(context.Background())
+			recordFinish(record, &BuildResult{Result: gojenkins.STATUS_ABORTED})
+		} else {
+			recordFinish(record, &BuildResult{Result: ResultDetached})
+		}
+		return ErrCancelled
+	}
+	if err != nil {
+		return err
+	}
+	recordFinish(record, result)
+	fmt.Fprintln(out, "Finished with", result.Result, "in", result.Duration.Round(time.Second))
+	if !result.Succeeded() {
+		return errors.Errorf("build %d finished with %s", result.Number, result.Result)
+	}
+	return nil
 }
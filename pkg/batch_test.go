@@ -1,8 +1,18 @@
 package pkg
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg/fakejenkins"
 )
 
 func TestReadBatchJob(t *testing.T) {
@@ -93,7 +103,11 @@ BatchJobs:
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ReadBatchJob(tt.args.content); !reflect.DeepEqual(got, tt.want) {
+			got, err := ReadBatchJob(tt.args.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("ReadBatchJob() = %v, want %v", got, tt.want)
 			}
 		})
@@ -151,3 +165,331 @@ func TestGenerateParameterList(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateParameterListMatrix(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobBatch BatchJob
+		want     []map[string]string
+	}{
+		{
+			name: "TestProduct",
+			jobBatch: BatchJob{
+				Job:       "Example/Folder/mainbranch",
+				Mode:      ModeMatrix,
+				Variables: map[string]string{"dry_run": "false"},
+				Substitute: map[string][]string{
+					"tenant": {"a", "b"},
+					"region": {"x", "y"},
+				},
+			},
+			want: []map[string]string{
+				{"region": "x", "tenant": "a", "dry_run": "false"},
+				{"region": "x", "tenant": "b", "dry_run": "false"},
+				{"region": "y", "tenant": "a", "dry_run": "false"},
+				{"region": "y", "tenant": "b", "dry_run": "false"},
+			},
+		},
+		{
+			name: "TestExcludeInclude",
+			jobBatch: BatchJob{
+				Job:  "Example/Folder/mainbranch",
+				Mode: ModeMatrix,
+				Substitute: map[string][]string{
+					"tenant": {"a", "b"},
+					"region": {"x", "y"},
+				},
+				Exclude: []map[string]string{
+					{"tenant": "b", "region": "y"},
+				},
+				Include: []map[string]string{
+					{"tenant": "a", "canary": "true"},
+					{"tenant": "c", "region": "z"},
+				},
+			},
+			want: []map[string]string{
+				{"region": "x", "tenant": "a", "canary": "true"},
+				{"region": "x", "tenant": "b"},
+				{"region": "y", "tenant": "a", "canary": "true"},
+				{"region": "z", "tenant": "c"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenerateParameterList(tt.jobBatch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GenerateParameterList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBatchJob(t *testing.T) {
+	tests := []struct {
+		name string
+		jl   JobList
+		want bool
+	}{
+		{
+			name: "TestUnequalZip",
+			jl: JobList{Batch: []BatchJob{{
+				Job:        "Example/Folder/mainbranch",
+				Substitute: map[string][]string{"tenant": {"a", "b"}, "region": {"x"}},
+			}}},
+			want: false,
+		},
+		{
+			name: "TestUnequalMatrix",
+			jl: JobList{Batch: []BatchJob{{
+				Job:        "Example/Folder/mainbranch",
+				Mode:       ModeMatrix,
+				Substitute: map[string][]string{"tenant": {"a", "b"}, "region": {"x"}},
+			}}},
+			want: true,
+		},
+		{
+			name: "TestExcludeWithoutMatrix",
+			jl: JobList{Batch: []BatchJob{{
+				Job:        "Example/Folder/mainbranch",
+				Substitute: map[string][]string{"tenant": {"a", "b"}},
+				Exclude:    []map[string]string{{"tenant": "a"}},
+			}}},
+			want: false,
+		},
+		{
+			name: "TestUnknownMode",
+			jl: JobList{Batch: []BatchJob{{
+				Job:  "Example/Folder/mainbranch",
+				Mode: "cartesian",
+			}}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateBatchJob(tt.jl); got != tt.want {
+				t.Errorf("ValidateBatchJob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInvalidBatchJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "TestUnequalZip", content: "BatchJobs:\n- Job: Example/Folder/mainbranch\n  Substitute:\n    tenant: [a, b]\n    region: [x]\n"},
+		{name: "TestUnknownMode", content: "BatchJobs:\n- Job: Example/Folder/mainbranch\n  Mode: cartesian\n"},
+		{name: "TestIncludeWithoutMatrix", content: "BatchJobs:\n- Job: Example/Folder/mainbranch\n  Include:\n  - tenant: c\n"},
+		{name: "TestStageCycle", content: "Stages:\n- Name: a\n  DependsOn: [b]\n- Name: b\n  DependsOn: [a]\n"},
+		{name: "TestNotYAML", content: "BatchJobs: [\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadBatchJob([]byte(tt.content)); err == nil {
+				t.Errorf("ReadBatchJob() accepted %q", tt.content)
+			}
+		})
+	}
+}
+
+func TestReadBatchJobRunPolicy(t *testing.T) {
+	yes, no := true, false
+	got, err := ReadBatchJob([]byte(`
+MaxParallel: 5
+FailFast: true
+BatchJobs:
+- Job: Example/Folder/mainbranch
+  MaxParallel: 2
+  FailFast: false
+  AbortRunning: true
+  Substitute:
+    tenant: [tenant1, tenant2, tenant3]
+- Job: Example/Folder/other
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxParallel != 5 || got.FailFast == nil || !*got.FailFast {
+		t.Fatalf("ReadBatchJob() top level policy = %+v", got.RunPolicy)
+	}
+
+	tests := []struct {
+		name string
+		job  BatchJob
+		want RunPolicy
+	}{
+		{
+			name: "TestOverride",
+			job:  got.Batch[0],
+			want: RunPolicy{MaxParallel: 2, FailFast: &no, AbortRunning: &yes},
+		},
+		{
+			name: "TestInherit",
+			job:  got.Batch[1],
+			want: RunPolicy{MaxParallel: 5, FailFast: &yes},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if policy := got.RunPolicy.Merge(tt.job.RunPolicy); !reflect.DeepEqual(policy, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", policy, tt.want)
+			}
+		})
+	}
+}
+
+// batchClient remembers how many builds were running whenever a run of a
+// batch started.
+type batchClient struct {
+	*JenkinsClient
+	server *fakejenkins.Server
+	job    string
+
+	mu      sync.Mutex
+	running []int
+}
+
+func (c *batchClient) Invoke(ctx context.Context, job *gojenkins.Job, choices map[string]string) (int64, error) {
+	running := 0
+	for _, build := range c.server.Builds("example", c.job) {
+		if build.Running {
+			running++
+		}
+	}
+	c.mu.Lock()
+	c.running = append(c.running, running)
+	c.mu.Unlock()
+	return c.JenkinsClient.Invoke(ctx, job, choices)
+}
+
+func TestInvokeBatchJob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goose-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldIndex, oldHistory := jobIndexPath, historyPath
+	defer func() { jobIndexPath, historyPath = oldIndex, oldHistory }()
+	jobIndexPath = filepath.Join(dir, "index")
+	historyPath = filepath.Join(dir, "history")
+
+	yes := true
+	tests := []struct {
+		name       string
+		job        string
+		runs       []string
+		policy     RunPolicy
+		wantErr    bool
+		wantStatus []string
+		// results of the builds on jenkins and in the history, sorted
+		wantBuilds  []string
+		wantHistory []string
+	}{
+		{
+			name:        "TestMaxParallel",
+			job:         "example/batch/quick",
+			runs:        []string{"a", "b", "c"},
+			policy:      RunPolicy{MaxParallel: 2},
+			wantStatus:  []string{RunSuccess, RunSuccess, RunSuccess},
+			wantBuilds:  []string{"SUCCESS", "SUCCESS", "SUCCESS"},
+			wantHistory: []string{"SUCCESS", "SUCCESS", "SUCCESS"},
+		},
+		{
+			name:        "TestFailFast",
+			job:         "example/batch/quick",
+			runs:        []string{"bad", "b", "c"},
+			policy:      RunPolicy{MaxParallel: 1, FailFast: &yes},
+			wantErr:     true,
+			wantStatus:  []string{RunFailure, RunCancelled, RunCancelled},
+			wantBuilds:  []string{"FAILURE"},
+			wantHistory: []string{"FAILURE"},
+		},
+		{
+			name:        "TestAbortRunning",
+			job:         "example/batch/hanging",
+			runs:        []string{"a", "bad"},
+			policy:      RunPolicy{FailFast: &yes, AbortRunning: &yes},
+			wantErr:     true,
+			wantStatus:  []string{RunCancelled, RunFailure},
+			wantBuilds:  []string{"ABORTED", "FAILURE"},
+			wantHistory: []string{"ABORTED", "FAILURE"},
+		},
+		{
+			name:        "TestFailFastDetached",
+			job:         "example/batch/hanging",
+			runs:        []string{"a", "bad"},
+			policy:      RunPolicy{FailFast: &yes},
+			wantErr:     true,
+			wantStatus:  []string{RunCancelled, RunFailure},
+			wantHistory: []string{ResultDetached, "FAILURE"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			params := []gojenkins.ParameterDefinition{{Name: "run", Type: StringParameter}}
+			fail := map[string]string{"run": "bad"}
+			server := fakejenkins.New(&fakejenkins.Team{Name: "example", Jobs: []*fakejenkins.Job{{Name: "example", Jobs: []*fakejenkins.Job{
+				{Name: "batch", Jobs: []*fakejenkins.Job{
+					{Name: "quick", Params: params, FailWhen: fail},
+					{Name: "hanging", Params: params, FailWhen: fail, Hang: true},
+				}},
+			}}}})
+			defer server.Close()
+			J, err := gojenkins.CreateJenkins(nil, server.LoginURL(), fakejenkins.User, fakejenkins.Token).Init(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &batchClient{JenkinsClient: NewJenkinsClient(J, Topology{Root: server.URL, Login: server.LoginURL()}), server: server, job: tt.job}
+
+			status := NewBatchStatus()
+			job := BatchJob{Job: tt.job, Substitute: map[string][]string{"run": tt.runs}}
+			err = InvokeBatchJob(client, job, BatchOptions{RunPolicy: tt.policy, Status: status})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InvokeBatchJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := []string{}
+			for _, run := range status.Snapshot() {
+				got = append(got, run.Status)
+			}
+			if !reflect.DeepEqual(got, tt.wantStatus) {
+				t.Errorf("run statuses = %v, want %v", got, tt.wantStatus)
+			}
+			builds := []string{}
+			for _, build := range server.Builds("example", tt.job) {
+				builds = append(builds, build.Result)
+			}
+			sort.Strings(builds)
+			if tt.wantBuilds != nil && !reflect.DeepEqual(builds, tt.wantBuilds) {
+				t.Errorf("builds on jenkins = %v, want %v", builds, tt.wantBuilds)
+			}
+			records, err := ListHistory(HistoryFilter{Job: tt.job})
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorded := []string{}
+			for _, record := range records {
+				if strings.HasPrefix(record.URL, server.URL) {
+					recorded = append(recorded, record.Result)
+				}
+			}
+			sort.Strings(recorded)
+			if !reflect.DeepEqual(recorded, tt.wantHistory) {
+				t.Errorf("results in the history = %v, want %v", recorded, tt.wantHistory)
+			}
+			if tt.policy.MaxParallel > 0 {
+				for _, running := range client.running {
+					if running >= tt.policy.MaxParallel {
+						t.Errorf("a run started while %d builds were running, MaxParallel is %d", running, tt.policy.MaxParallel)
+					}
+				}
+			}
+		})
+	}
+}
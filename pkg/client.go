@@ -0,0 +1,172 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// Client is the jenkins goose works with. Jobs are named by their path,
+// e.g. Example/Folder/main, whose first part is the team. JenkinsClient
+// talks to a real jenkins.
+//
+// Looking jobs up, triggering them, the queues, reading consoles and
+// running scripts all go through Client, so another backend is plugged in
+// by implementing it. Jobs and builds are still described with gojenkins
+// types, and polling, stopping or inspecting a started build goes through
+// that build's own Requester, so a backend that isn't jenkins also has to
+// serve that part of the jenkins API, like pkg/fakejenkins does.
+// Batch runs call a Client from many goroutines at once, so it has to be
+// safe for concurrent use.
+type Client interface {
+	// Team is the team, or controller, the job at path belongs to.
+	Team(path string) string
+	// GetJob finds the job or folder at path.
+	GetJob(ctx context.Context, path string) (*gojenkins.Job, error)
+	// ListJobs lists the jobs in the folder at path. When path is empty it
+	// lists the teams, or the jobs at the root of a flat jenkins.
+	ListJobs(ctx context.Context, path string) ([]gojenkins.InnerJob, error)
+	// GetParameters returns the parameters of the job at path in the order
+	// they are declared.
+	GetParameters(ctx context.Context, path string) ([]gojenkins.ParameterDefinition, error)
+	// Invoke queues a build of job and returns its queue id, or 0 if the job
+	// is already queued. Values of file parameters are paths of local files
+	// to upload.
+	Invoke(ctx context.Context, job *gojenkins.Job, choices map[string]string) (int64, error)
+	// GetQueueItem returns an item of a team's queue, which may have left
+	// the queue as a build already.
+	GetQueueItem(ctx context.Context, team string, id int64) (*gojenkins.Task, error)
+	// Teams lists the teams.
+	Teams(ctx context.Context) ([]string, error)
+	// GetQueue lists the builds waiting in a team's queue.
+	GetQueue(ctx context.Context, team string) ([]QueueItem, error)
+	// CancelQueueItem takes a build off a team's queue.
+	CancelQueueItem(ctx context.Context, team string, id int64) error
+	// GetBuild returns a build of the job at path, or its last build when
+	// number is 0.
+	GetBuild(ctx context.Context, path string, number int64) (*gojenkins.Build, error)
+	// GetConsole returns the console of a build from offset on.
+	GetConsole(ctx context.Context, build *gojenkins.Build, offset int64) (ConsoleChunk, error)
+	// RunScript runs a groovy script on a team's script console and returns
+	// what it printed.
+	RunScript(ctx context.Context, team string, script string) (string, error)
+}
+
+// ConsoleChunk is a piece of a build's console. Offset is where the next
+// piece starts, and More is set while jenkins has more to send.
+type ConsoleChunk struct {
+	Text   string
+	Offset int64
+	More   bool
+}
+
+// JenkinsClient is a Client backed by gojenkins. Every team is reached
+// through a view of J on the controller the topology names, J itself is
+// never changed.
+type JenkinsClient struct {
+	J        *gojenkins.Jenkins
+	Topology Topology
+}
+
+// NewJenkinsClient wraps a connected gojenkins client.
+func NewJenkinsClient(J *gojenkins.Jenkins, topology Topology) *JenkinsClient {
+	return &JenkinsClient{J: J, Topology: topology}
+}
+
+func (c *JenkinsClient) Team(path string) string {
+	return c.Topology.Team(path)
+}
+
+func (c *JenkinsClient) GetJob(ctx context.Context, path string) (*gojenkins.Job, error) {
+	return GetNestedJob(c.J, c.Topology, path)
+}
+
+func (c *JenkinsClient) ListJobs(ctx context.Context, path string) ([]gojenkins.InnerJob, error) {
+	if path == "" {
+		return topFolders(c.J, c.Topology)
+	}
+	job, err := c.GetJob(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return job.Raw.Jobs, nil
+}
+
+func (c *JenkinsClient) GetParameters(ctx context.Context, path string) ([]gojenkins.ParameterDefinition, error) {
+	job, err := c.GetJob(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return job.GetParameters(ctx)
+}
+
+func (c *JenkinsClient) Invoke(ctx context.Context, job *gojenkins.Job, choices map[string]string) (int64, error) {
+	return invokeBuild(ctx, job, choices)
+}
+
+func (c *JenkinsClient) GetQueueItem(ctx context.Context, team string, id int64) (*gojenkins.Task, error) {
+	return c.Topology.View(c.J, team).GetQueueItem(ctx, id)
+}
+
+func (c *JenkinsClient) Teams(ctx context.Context) ([]string, error) {
+	return GetTeams(c.J, c.Topology)
+}
+
+func (c *JenkinsClient) GetQueue(ctx context.Context, team string) ([]QueueItem, error) {
+	return GetQueueItems(c.J, c.Topology, []string{team})
+}
+
+func (c *JenkinsClient) CancelQueueItem(ctx context.Context, team string, id int64) error {
+	return CancelQueueItem(c.J, c.Topology, team, id)
+}
+
+func (c *JenkinsClient) GetBuild(ctx context.Context, path string, number int64) (*gojenkins.Build, error) {
+	job, err := c.GetJob(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var build *gojenkins.Build
+	if number == 0 {
+		build, err = job.GetLastBuild(ctx)
+	} else {
+		build, err = job.GetBuild(ctx, number)
+	}
+	if err != nil || build == nil {
+		return nil, errors.Errorf("could not find build %d of %s: %v", number, path, err)
+	}
+	return build, nil
+}
+
+func (c *JenkinsClient) GetConsole(ctx context.Context, build *gojenkins.Build, offset int64) (ConsoleChunk, error) {
+	resp, err := build.GetConsoleOutputFromIndex(ctx, offset)
+	return ConsoleChunk{Text: resp.Content, Offset: resp.Offset, More: resp.HasMoreText}, err
+}
+
+func (c *JenkinsClient) RunScript(ctx context.Context, team string, script string) (string, error) {
+	return RunScript(c.J, c.Topology, team, script)
+}
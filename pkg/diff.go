@@ -0,0 +1,276 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Kinds of DiffLine.
+const (
+	LineSame    = "same"
+	LineAdded   = "added"
+	LineRemoved = "removed"
+	LineSkipped = "skipped"
+)
+
+// ValueChange is a value that differs between two builds. A or B is empty
+// when the other build doesn't have it at all.
+type ValueChange struct {
+	Name string `json:"name" yaml:"name"`
+	A    string `json:"a" yaml:"a"`
+	B    string `json:"b" yaml:"b"`
+}
+
+// Change is a commit recorded in the changeset of a build.
+type Change struct {
+	Build    int64  `json:"build" yaml:"build"`
+	CommitID string `json:"commitId" yaml:"commitId"`
+	Author   string `json:"author" yaml:"author"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// DiffLine is a line of the console diff. Skipped lines stand for a run
+// of unchanged lines that were left out.
+type DiffLine struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Text string `json:"text" yaml:"text"`
+}
+
+// BuildDiff is what changed from build A to build B of a job.
+type BuildDiff struct {
+	A          *BuildResult
+	B          *BuildResult
+	Parameters []ValueChange
+	Revisions  []ValueChange
+	// Changes are the commits built by the builds after the older of A and
+	// B, up to the newer one.
+	Changes []Change
+	// TestsA and TestsB are nil when the build has no test report, and
+	// Tests is nil unless both have one.
+	TestsA  *TestReport
+	TestsB  *TestReport
+	Tests   *TestComparison
+	Console []DiffLine
+}
+
+var (
+	// 2021-03-01T10:00:00.123Z, 2021-03-01 10:00:00,123 +0000 and friends
+	dateTimePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}([.,]\d+)?(Z|\s?[+-]\d{2}:?\d{2})?`)
+	// 10:00:00 and 10:00:00.123, as printed by the timestamper plugin
+	timePattern = regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}([.,]\d+)?\b`)
+)
+
+// DiffBuilds compares builds a and b of thejob. Console lines are compared
+// after NormalizeConsole, and unchanged lines further than contextLines from
+// a change are skipped.
+func DiffBuilds(ctx context.Context, thejob *gojenkins.Job, a *gojenkins.Build, b *gojenkins.Build, contextLines int) (*BuildDiff, error) {
+	diff := &BuildDiff{
+		A:         NewBuildResult(a),
+		B:         NewBuildResult(b),
+		Revisions: DiffValues(BuildRevisions(a), BuildRevisions(b)),
+	}
+	diff.Parameters = DiffValues(diff.A.Parameters, diff.B.Parameters)
+
+	diff.Changes = changesBetween(ctx, thejob, a.GetBuildNumber(), b.GetBuildNumber())
+
+	var err error
+	if diff.TestsA, err = GetTestReport(ctx, a); err != nil && err != ErrNoTestReport {
+		return nil, err
+	}
+	if diff.TestsB, err = GetTestReport(ctx, b); err != nil && err != ErrNoTestReport {
+		return nil, err
+	}
+	if diff.TestsA != nil && diff.TestsB != nil {
+		comparison := CompareTestReports(diff.TestsB, diff.TestsA)
+		diff.Tests = &comparison
+	}
+
+	consoleA := NormalizeConsole(a.GetConsoleOutput(ctx), a.GetBuildNumber())
+	consoleB := NormalizeConsole(b.GetConsoleOutput(ctx), b.GetBuildNumber())
+	diff.Console = DiffConsole(consoleA, consoleB, contextLines)
+	return diff, nil
+}
+
+// DurationChange is how much longer B took than A.
+func (d *BuildDiff) DurationChange() time.Duration {
+	return d.B.Duration - d.A.Duration
+}
+
+// DiffValues lists the names whose values differ between a and b, sorted
+// by name.
+func DiffValues(a map[string]string, b map[string]string) []ValueChange {
+	changes := []ValueChange{}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			changes = append(changes, ValueChange{Name: name, A: value, B: other})
+		}
+	}
+	for name, value := range b {
+		if _, ok := a[name]; !ok {
+			changes = append(changes, ValueChange{Name: name, B: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// BuildRevisions returns the revision a build checked out of each of its
+// repositories, keyed by the repository's url.
+func BuildRevisions(B *gojenkins.Build) map[string]string {
+	revisions := map[string]string{}
+	for _, action := range B.Raw.Actions {
+		if action.LastBuiltRevision.SHA1 == "" {
+			continue
+		}
+		repo := action.ScmName
+		if len(action.RemoteUrls) > 0 {
+			repo = action.RemoteUrls[0]
+		}
+		if repo == "" {
+			repo = "scm"
+		}
+		revisions[repo] = action.LastBuiltRevision.SHA1
+	}
+	return revisions
+}
+
+// BuildChanges returns the commits in the changesets of a build.
+func BuildChanges(B *gojenkins.Build) []Change {
+	changes := []Change{}
+	number := B.GetBuildNumber()
+	for _, item := range B.Raw.ChangeSet.Items {
+		changes = append(changes, Change{Build: number, CommitID: item.CommitID, Author: item.Author.FullName, Message: strings.TrimSpace(item.Msg)})
+	}
+	for _, changeSet := range B.Raw.ChangeSets {
+		for _, item := range changeSet.Items {
+			changes = append(changes, Change{Build: number, CommitID: item.CommitID, Author: item.Author.FullName, Message: strings.TrimSpace(item.Msg)})
+		}
+	}
+	return changes
+}
+
+// changesBetween collects the changes of the builds after the older of a
+// and b up to the newer one. Builds that were deleted are skipped.
+func changesBetween(ctx context.Context, thejob *gojenkins.Job, a int64, b int64) []Change {
+	if a > b {
+		a, b = b, a
+	}
+	changes := []Change{}
+	for n := a + 1; n <= b; n++ {
+		build, err := thejob.GetBuild(ctx, n)
+		if err != nil || build == nil {
+			continue
+		}
+		changes = append(changes, BuildChanges(build)...)
+	}
+	return changes
+}
+
+// NormalizeConsole strips what changes from build to build without
+// meaning anything, timestamps and the build's own number, so two console
+// logs can be compared. Only numbers that look like they name the build
+// are replaced: #42, /42/, BUILD_NUMBER=42 and "build 42".
+func NormalizeConsole(console string, number int64) string {
+	console = dateTimePattern.ReplaceAllString(console, "<time>")
+	console = timePattern.ReplaceAllString(console, "<time>")
+	buildNumber := regexp.MustCompile(fmt.Sprintf(`(#|/|=|[Bb]uild )%d\b`, number))
+	return buildNumber.ReplaceAllString(console, "${1}<build>")
+}
+
+// DiffConsole compares two console logs line by line. Unchanged lines more
+// than contextLines away from a change are replaced by a LineSkipped.
+func DiffConsole(a string, b string, contextLines int) []DiffLine {
+	if a != "" && !strings.HasSuffix(a, "\n") {
+		a += "\n"
+	}
+	if b != "" && !strings.HasSuffix(b, "\n") {
+		b += "\n"
+	}
+	dmp := diffmatchpatch.New()
+	charsA, charsB, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(charsA, charsB, false), lines)
+
+	all := []DiffLine{}
+	for _, d := range diffs {
+		kind := LineSame
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			kind = LineAdded
+		case diffmatchpatch.DiffDelete:
+			kind = LineRemoved
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				all = append(all, DiffLine{Kind: kind, Text: strings.TrimSuffix(line, "\n")})
+			}
+		}
+	}
+	return trimContext(all, contextLines)
+}
+
+// trimContext replaces the runs of unchanged lines that are further than
+// contextLines from a change by a single LineSkipped.
+func trimContext(lines []DiffLine, contextLines int) []DiffLine {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Kind == LineSame {
+			continue
+		}
+		for j := i - contextLines; j <= i+contextLines; j++ {
+			if j >= 0 && j < len(lines) {
+				keep[j] = true
+			}
+		}
+	}
+
+	trimmed := []DiffLine{}
+	skipped := 0
+	for i, line := range lines {
+		if keep[i] {
+			if skipped > 0 {
+				trimmed = append(trimmed, DiffLine{Kind: LineSkipped, Text: fmt.Sprintf("%d unchanged lines", skipped)})
+				skipped = 0
+			}
+			trimmed = append(trimmed, line)
+			continue
+		}
+		skipped++
+	}
+	if skipped > 0 && len(trimmed) > 0 {
+		trimmed = append(trimmed, DiffLine{Kind: LineSkipped, Text: fmt.Sprintf("%d unchanged lines", skipped)})
+	}
+	return trimmed
+}
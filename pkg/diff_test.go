@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestDiffValues(t *testing.T) {
+	a := map[string]string{"env": "staging", "dry_run": "true", "removed": "x"}
+	b := map[string]string{"env": "prod", "dry_run": "true", "added": "y"}
+	want := []ValueChange{
+		{Name: "added", B: "y"},
+		{Name: "env", A: "staging", B: "prod"},
+		{Name: "removed", A: "x"},
+	}
+	if got := DiffValues(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffValues() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildRevisionsAndChanges(t *testing.T) {
+	raw := &gojenkins.BuildResponse{}
+	err := json.Unmarshal([]byte(`{"number": 12, "actions": [
+		{"_class": "hudson.model.ParametersAction"},
+		{"lastBuiltRevision": {"SHA1": "abc123"}, "remoteUrls": ["git@github.com:example/app.git"]},
+		{"lastBuiltRevision": {"SHA1": "def456"}, "scmName": "shared-lib"}
+	], "changeSets": [{"items": [
+		{"commitId": "abc123", "msg": "Fix parser\n", "author": {"fullName": "Ada"}}
+	]}]}`), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := &gojenkins.Build{Raw: raw}
+
+	revisions := map[string]string{"git@github.com:example/app.git": "abc123", "shared-lib": "def456"}
+	if got := BuildRevisions(build); !reflect.DeepEqual(got, revisions) {
+		t.Errorf("BuildRevisions() = %v, want %v", got, revisions)
+	}
+	changes := []Change{{Build: 12, CommitID: "abc123", Author: "Ada", Message: "Fix parser"}}
+	if got := BuildChanges(build); !reflect.DeepEqual(got, changes) {
+		t.Errorf("BuildChanges() = %+v, want %+v", got, changes)
+	}
+}
+
+func TestNormalizeConsole(t *testing.T) {
+	tests := []struct {
+		console string
+		want    string
+	}{
+		{"[2021-03-01T10:00:00.123Z] Started", "[<time>] Started"},
+		{"10:00:01 + make test", "<time> + make test"},
+		{"2021-03-01 10:00:00,123 +0000 INFO done", "<time> INFO done"},
+		{"Building #42 at https://ci/job/app/42/", "Building #<build> at https://ci/job/app/<build>/"},
+		{"BUILD_NUMBER=42, build 42", "BUILD_NUMBER=<build>, build <build>"},
+		{"Ran 42 tests in 420ms", "Ran 42 tests in 420ms"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeConsole(tt.console, 42); got != tt.want {
+			t.Errorf("NormalizeConsole(%q) = %q, want %q", tt.console, got, tt.want)
+		}
+	}
+}
+
+func TestDiffConsole(t *testing.T) {
+	a := "checkout\ncompile\ntest\npackage\nupload\npublish\n"
+	b := "checkout\ncompile\ntest\n1 test failed\npackage\nupload\npublish"
+	want := []DiffLine{
+		{Kind: LineSkipped, Text: "2 unchanged lines"},
+		{Kind: LineSame, Text: "test"},
+		{Kind: LineAdded, Text: "1 test failed"},
+		{Kind: LineSame, Text: "package"},
+		{Kind: LineSkipped, Text: "2 unchanged lines"},
+	}
+	if got := DiffConsole(a, b, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffConsole() = %+v, want %+v", got, want)
+	}
+	if got := DiffConsole(a, a, 3); len(got) != 0 {
+		t.Errorf("DiffConsole() of the same log = %+v, want nothing", got)
+	}
+}
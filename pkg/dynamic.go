@@ -0,0 +1,150 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// The Active Choices plugin's choice parameter kinds. Their choices are
+// computed by a script on the controller, possibly from the values of other
+// parameters. Its reference parameters render HTML rather than choices and
+// are asked for like any other parameter.
+const (
+	ActiveChoiceParameter  = "ChoiceParameter"
+	CascadeChoiceParameter = "CascadeChoiceParameter"
+)
+
+// IsDynamicParameter reports whether the choices of param have to be asked
+// from jenkins instead of read from its definition.
+func IsDynamicParameter(param gojenkins.ParameterDefinition) bool {
+	switch param.Type {
+	case ActiveChoiceParameter, CascadeChoiceParameter:
+		return true
+	}
+	return false
+}
+
+// ResolveChoices asks jenkins for the choices of a dynamic parameter given
+// the values picked so far. It also returns the choice the parameter's
+// script marked as selected, if any. Values of password and file parameters
+// are left out of the script. The error's cause is ErrScriptDenied when the
+// user may not use the script console.
+func ResolveChoices(client Client, thejob *gojenkins.Job, param gojenkins.ParameterDefinition, answers map[string]string) ([]string, string, error) {
+	answers = scriptAnswers(thejob, answers)
+	output, err := client.RunScript(context.TODO(), client.Team(thejob.Raw.FullName), choicesScript(thejob.Raw.FullName, param.Name, answers))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "could not get the choices of %s", param.Name)
+	}
+	choices, selected := parseChoices(output)
+	return choices, selected, nil
+}
+
+// secretParameters names the job's password and file parameters.
+func secretParameters(thejob *gojenkins.Job) map[string]bool {
+	secret := map[string]bool{}
+	if thejob.Raw == nil {
+		return secret
+	}
+	for _, property := range thejob.Raw.Property {
+		for _, param := range property.ParameterDefinitions {
+			if param.Type == PasswordParameter || param.Type == FileParameter {
+				secret[param.Name] = true
+			}
+		}
+	}
+	return secret
+}
+
+// scriptAnswers drops the answers to the job's password and file parameters,
+// which don't belong in a script.
+func scriptAnswers(thejob *gojenkins.Job, answers map[string]string) map[string]string {
+	hidden := secretParameters(thejob)
+	kept := map[string]string{}
+	for name, value := range answers {
+		if !hidden[name] {
+			kept[name] = value
+		}
+	}
+	return kept
+}
+
+// choicesScript prints one choice per line. Active Choices parameters return
+// their choices as a map of value to display name.
+func choicesScript(job string, name string, answers map[string]string) string {
+	names := make([]string, 0, len(answers))
+	for answer := range answers {
+		names = append(names, answer)
+	}
+	sort.Strings(names)
+	values := []string{}
+	for _, answer := range names {
+		values = append(values, fmt.Sprintf("%s: %s", groovyString(answer), groovyString(answers[answer])))
+	}
+	if len(values) == 0 {
+		values = append(values, ":")
+	}
+
+	return fmt.Sprintf(`def job = Jenkins.instance.getItemByFullName(%s)
+def param = job.getProperty(hudson.model.ParametersDefinitionProperty).getParameterDefinition(%s)
+def choices = param.getChoices([%s])
+(choices instanceof Map ? choices.keySet() : choices).each { println it }
+null`, groovyString(job), groovyString(name), strings.Join(values, ", "))
+}
+
+// groovyString quotes s as a single quoted groovy string.
+func groovyString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return "'" + s + "'"
+}
+
+// parseChoices reads the script output. Active Choices scripts mark the
+// default with a ":selected" suffix and hide choices with ":disabled".
+func parseChoices(output string) ([]string, string) {
+	choices := []string{}
+	selected := ""
+	for _, line := range strings.Split(output, "\n") {
+		choice := strings.TrimSpace(line)
+		switch {
+		case choice == "" || strings.HasSuffix(choice, ":disabled"):
+			continue
+		case strings.HasSuffix(choice, ":selected"):
+			choice = strings.TrimSuffix(choice, ":selected")
+			if selected == "" {
+				selected = choice
+			}
+		}
+		choices = append(choices, choice)
+	}
+	return choices, selected
+}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+	"github.com/rallyhealth/goose/pkg/fakejenkins"
+)
+
+func TestParseChoices(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		want         []string
+		wantSelected string
+	}{
+		{name: "TestPlain", output: "us-east-1\nus-west-2\n", want: []string{"us-east-1", "us-west-2"}},
+		{name: "TestSelected", output: "a\nb:selected\nc:disabled\n\n", want: []string{"a", "b"}, wantSelected: "b"},
+		{name: "TestEmpty", output: "", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, selected := parseChoices(tt.output)
+			if !reflect.DeepEqual(got, tt.want) || selected != tt.wantSelected {
+				t.Errorf("parseChoices() = %v, %q, want %v, %q", got, selected, tt.want, tt.wantSelected)
+			}
+		})
+	}
+}
+
+func TestChoicesScript(t *testing.T) {
+	script := choicesScript("Example/Folder/main", "region", map[string]string{"tenant": "o'neil", "env": "prod"})
+	for _, want := range []string{
+		`getItemByFullName('Example/Folder/main')`,
+		`getParameterDefinition('region')`,
+		`getChoices(['env': 'prod', 'tenant': 'o\'neil'])`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("choicesScript() = %s, missing %s", script, want)
+		}
+	}
+
+	if script := choicesScript("Example/Folder/main", "tenant", nil); !strings.Contains(script, "getChoices([:])") {
+		t.Errorf("choicesScript() without answers = %s", script)
+	}
+}
+
+func TestScriptAnswers(t *testing.T) {
+	job := &gojenkins.Job{Raw: &gojenkins.JobResponse{}}
+	job.Raw.Property = append(job.Raw.Property, struct {
+		ParameterDefinitions []gojenkins.ParameterDefinition `json:"parameterDefinitions"`
+	}{ParameterDefinitions: []gojenkins.ParameterDefinition{
+		{Name: "tenant", Type: ChoiceParameter},
+		{Name: "token", Type: PasswordParameter},
+		{Name: "manifest", Type: FileParameter},
+	}})
+
+	got := scriptAnswers(job, map[string]string{"tenant": "a", "token": "hunter2", "manifest": "./manifest.yaml"})
+	if want := map[string]string{"tenant": "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scriptAnswers() = %v, want %v", got, want)
+	}
+}
+
+func TestIsDynamicParameter(t *testing.T) {
+	for kind, want := range map[string]bool{
+		ActiveChoiceParameter:       true,
+		CascadeChoiceParameter:      true,
+		"DynamicReferenceParameter": false,
+		ChoiceParameter:             false,
+	} {
+		if got := IsDynamicParameter(gojenkins.ParameterDefinition{Type: kind}); got != want {
+			t.Errorf("IsDynamicParameter(%s) = %v, want %v", kind, got, want)
+		}
+	}
+}
+
+func TestResolveChoicesScriptDenied(t *testing.T) {
+	server := fakejenkins.New(&fakejenkins.Team{Name: "example", DenyScripts: true})
+	defer server.Close()
+	J, err := gojenkins.CreateJenkins(nil, server.LoginURL(), fakejenkins.User, fakejenkins.Token).Init(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewJenkinsClient(J, Topology{Root: server.URL, Login: server.LoginURL()})
+
+	job := &gojenkins.Job{Raw: &gojenkins.JobResponse{FullName: "example/app/master"}}
+	_, _, err = ResolveChoices(client, job, gojenkins.ParameterDefinition{Name: "tenant", Type: ActiveChoiceParameter}, nil)
+	if errors.Cause(err) != ErrScriptDenied {
+		t.Errorf("ResolveChoices() error = %v, want %v", err, ErrScriptDenied)
+	}
+}
@@ -0,0 +1,721 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Package fakejenkins is an in-process Jenkins for tests. It serves the
+// CloudBees layout goose expects: the login url lists the teams, and each
+// team has its own controller under /teams-<team> with folders, jobs, a
+// queue, builds and their artifacts, a progressive console and the script
+// console. A team without a name is served at the root instead, like a
+// plain jenkins.
+package fakejenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+)
+
+// Credentials the server accepts.
+const (
+	User  = "goose@example.com"
+	Token = "fake-api-token"
+)
+
+// Job is a job or, when it has Jobs, a folder.
+type Job struct {
+	Name   string
+	Params []gojenkins.ParameterDefinition
+	Jobs   []*Job
+	// Repo is the git url the index script reports for the job.
+	Repo string
+	// Console is printed by every build started from the server, one line
+	// per console poll, and Result is how they end (SUCCESS by default).
+	Console []string
+	Result  string
+	// FailWhen fails the builds whose parameters have all of its values
+	// right away, without printing Console.
+	FailWhen map[string]string
+	// Hang keeps the other builds running after Console until they are
+	// stopped.
+	Hang   bool
+	Builds []*Build
+}
+
+// Build is a build of a Job. Builds given to New have finished unless
+// Running is set.
+type Build struct {
+	Number     int64
+	QueueID    int64
+	Parameters map[string]string
+	Console    []string
+	Result     string
+	Running    bool
+	Started    time.Time
+	Duration   time.Duration
+	// Artifacts maps the relative path of every archived file to its
+	// content. ArtifactDelay stalls each download halfway through.
+	Artifacts     map[string]string
+	ArtifactDelay time.Duration
+
+	// lines of Console printed so far while Running
+	printed int
+	// hang keeps the build running before its last line
+	hang bool
+}
+
+// QueueItem is a build held in a team's queue.
+type QueueItem struct {
+	ID int64
+	// Job is the full name of the job, e.g. example/app/main
+	Job        string
+	Why        string
+	Parameters map[string]string
+	Since      time.Time
+	Blocked    bool
+	Stuck      bool
+}
+
+// Team is a team controller. Its Jobs are the items at the root of the
+// controller, usually a single folder named after the team. The team named
+// "" is the jenkins at the root of the server.
+type Team struct {
+	Name  string
+	Jobs  []*Job
+	Queue []*QueueItem
+	// DenyScripts answers the script console with 403 Forbidden, like a
+	// controller where the user isn't an administrator.
+	DenyScripts bool
+}
+
+// Server is a running fake Jenkins.
+type Server struct {
+	*httptest.Server
+
+	// Script answers the script console. When nil, scripts listing the
+	// SCMs of jobs are answered from the jobs' Repo and others print nothing.
+	Script func(team string, script string) string
+
+	mu          sync.Mutex
+	teams       []*Team
+	nextQueueID int64
+	started     map[int64]startedItem
+}
+
+// startedItem is a queue item that left the queue as a build.
+type startedItem struct {
+	team   *Team
+	job    *Job
+	path   []string
+	number int64
+}
+
+// New starts a fake Jenkins serving teams. Close it when done.
+func New(teams ...*Team) *Server {
+	s := &Server{teams: teams, nextQueueID: 1000, started: map[int64]startedItem{}}
+	// Handlers read s.URL, so it is set before the server starts serving
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serve))
+	s.Start()
+	return s
+}
+
+// LoginURL is the url of the operations center listing the teams.
+func (s *Server) LoginURL() string {
+	return s.URL + "/cjoc"
+}
+
+// Env returns the JENKINS_* variables pointing goose at the server.
+func (s *Server) Env() map[string]string {
+	return map[string]string{
+		"JENKINS_EMAIL":     User,
+		"JENKINS_API_KEY":   Token,
+		"JENKINS_ROOT_URL":  s.URL,
+		"JENKINS_LOGIN_URL": s.LoginURL(),
+	}
+}
+
+// Builds returns a copy of the builds of the job at path, e.g.
+// example/app/main, oldest first.
+func (s *Server) Builds(team string, path string) []Build {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.team(team)
+	if t == nil {
+		return nil
+	}
+	job, _ := findJob(t.Jobs, strings.Split(path, "/"))
+	if job == nil {
+		return nil
+	}
+	builds := []Build{}
+	for _, b := range job.Builds {
+		builds = append(builds, *b)
+	}
+	return builds
+}
+
+// Queue returns a copy of the items held in a team's queue.
+func (s *Server) Queue(team string) []QueueItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := []QueueItem{}
+	if t := s.team(team); t != nil {
+		for _, item := range t.Queue {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+func (s *Server) team(name string) *Team {
+	for _, t := range s.teams {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func findJob(jobs []*Job, path []string) (*Job, []string) {
+	var job *Job
+	for _, name := range path {
+		job = nil
+		for _, candidate := range jobs {
+			if sameName(candidate.Name, name) {
+				job = candidate
+			}
+		}
+		if job == nil {
+			return nil, nil
+		}
+		jobs = job.Jobs
+	}
+	return job, path
+}
+
+// sameName tells whether a request names a job. Like multibranch projects,
+// jobs of branches with a slash are named e.g. feature%2Flogin, and goose
+// asks for them with the slash escaped only once.
+func sameName(jobName string, name string) bool {
+	if jobName == name {
+		return true
+	}
+	unescaped, err := url.PathUnescape(jobName)
+	return err == nil && unescaped == name
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if user, token, ok := r.BasicAuth(); !ok || user != User || token != Token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := []string{}
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	for _, segment := range strings.Split(path, "/") {
+		if path == "" {
+			break
+		}
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+
+	switch {
+	case len(segments) > 0 && segments[0] == "cjoc" && isAPI(segments[1:]):
+		teams := []gojenkins.InnerJob{}
+		for _, t := range s.teams {
+			if t.Name != "" {
+				teams = append(teams, gojenkins.InnerJob{Class: folderClass, Name: t.Name, Url: s.teamURL(t), Color: "blue"})
+			}
+		}
+		writeJSON(w, map[string]interface{}{"jobs": teams})
+	case len(segments) > 0 && strings.HasPrefix(segments[0], "teams-"):
+		t := s.team(strings.TrimPrefix(segments[0], "teams-"))
+		if t == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveTeam(w, r, t, segments[1:])
+	case s.team("") != nil:
+		s.serveTeam(w, r, s.team(""), segments)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func isAPI(segments []string) bool {
+	return len(segments) == 0 || (len(segments) == 2 && segments[0] == "api" && segments[1] == "json")
+}
+
+func (s *Server) serveTeam(w http.ResponseWriter, r *http.Request, t *Team, segments []string) {
+	switch {
+	case isAPI(segments):
+		writeJSON(w, map[string]interface{}{"jobs": s.innerJobs(t, nil, t.Jobs)})
+	case segments[0] == "crumbIssuer":
+		writeJSON(w, map[string]string{"crumbRequestField": "Jenkins-Crumb", "crumb": "fake-crumb"})
+	case segments[0] == "scriptText" && r.Method == http.MethodPost:
+		s.serveScript(w, r, t)
+	case segments[0] == "queue":
+		s.serveQueue(w, r, t, segments[1:])
+	case segments[0] == "job":
+		path := []string{}
+		for len(segments) >= 2 && segments[0] == "job" {
+			path = append(path, segments[1])
+			segments = segments[2:]
+		}
+		job, _ := findJob(t.Jobs, path)
+		if job == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveJob(w, r, t, job, path, segments)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveScript(w http.ResponseWriter, r *http.Request, t *Team) {
+	if t.DenyScripts {
+		http.Error(w, "goose is missing the Overall/Administer permission", http.StatusForbidden)
+		return
+	}
+	script := r.FormValue("script")
+	if s.Script != nil {
+		fmt.Fprint(w, s.Script(t.Name, script))
+		return
+	}
+	if strings.Contains(script, "getSCMs") {
+		var walk func(jobs []*Job, parents []string)
+		walk = func(jobs []*Job, parents []string) {
+			for _, job := range jobs {
+				path := append(append([]string{}, parents...), job.Name)
+				if job.Repo != "" {
+					fmt.Fprintf(w, "%s\t%s\n", strings.Join(path, "/"), job.Repo)
+				}
+				walk(job.Jobs, path)
+			}
+		}
+		walk(t.Jobs, nil)
+	}
+}
+
+func (s *Server) serveQueue(w http.ResponseWriter, r *http.Request, t *Team, segments []string) {
+	switch {
+	case isAPI(segments):
+		items := []map[string]interface{}{}
+		for _, item := range t.Queue {
+			items = append(items, s.queueItemJSON(t, item))
+		}
+		writeJSON(w, map[string]interface{}{"items": items})
+	case len(segments) == 1 && segments[0] == "cancelItem" && r.Method == http.MethodPost:
+		id, _ := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+		for i, item := range t.Queue {
+			if item.ID == id {
+				t.Queue = append(t.Queue[:i], t.Queue[i+1:]...)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case len(segments) >= 2 && segments[0] == "item" && isAPI(segments[2:]):
+		id, _ := strconv.ParseInt(segments[1], 10, 64)
+		for _, item := range t.Queue {
+			if item.ID == id {
+				writeJSON(w, s.queueItemJSON(t, item))
+				return
+			}
+		}
+		started, ok := s.started[id]
+		if !ok || started.team != t {
+			http.NotFound(w, r)
+			return
+		}
+		jobURL := s.jobURL(t, started.path)
+		writeJSON(w, map[string]interface{}{
+			"id":         id,
+			"task":       map[string]string{"name": started.job.Name, "url": jobURL},
+			"executable": map[string]interface{}{"number": started.number, "url": fmt.Sprintf("%s%d/", jobURL, started.number)},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) queueItemJSON(t *Team, item *QueueItem) map[string]interface{} {
+	path := strings.Split(item.Job, "/")
+	return map[string]interface{}{
+		"id":           item.ID,
+		"why":          item.Why,
+		"blocked":      item.Blocked,
+		"stuck":        item.Stuck,
+		"inQueueSince": item.Since.UnixNano() / int64(time.Millisecond),
+		"task":         map[string]string{"name": path[len(path)-1], "url": s.jobURL(t, path)},
+		"actions":      []interface{}{parametersAction(item.Parameters)},
+	}
+}
+
+func (s *Server) serveJob(w http.ResponseWriter, r *http.Request, t *Team, job *Job, path []string, segments []string) {
+	switch {
+	case isAPI(segments):
+		writeJSON(w, s.jobJSON(t, job, path))
+	case len(segments) == 1 && (segments[0] == "build" || segments[0] == "buildWithParameters") && r.Method == http.MethodPost:
+		s.trigger(w, r, t, job, path)
+	default:
+		number, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		for _, build := range job.Builds {
+			if build.Number == number {
+				s.serveBuild(w, r, t, build, path, segments[1:])
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}
+}
+
+// trigger starts a build right away, leaving a queue item that points at it.
+func (s *Server) trigger(w http.ResponseWriter, r *http.Request, t *Team, job *Job, path []string) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := map[string]string{}
+	for _, param := range job.Params {
+		params[param.Name] = fmt.Sprint(param.DefaultParameterValue.Value)
+		if param.DefaultParameterValue.Value == nil {
+			params[param.Name] = ""
+		}
+		if value, ok := r.PostForm[param.Name]; ok {
+			params[param.Name] = value[0]
+		}
+		if r.MultipartForm != nil {
+			if files := r.MultipartForm.File[param.Name]; len(files) > 0 {
+				params[param.Name] = files[0].Filename
+			}
+		}
+	}
+
+	result := job.Result
+	if result == "" {
+		result = gojenkins.STATUS_SUCCESS
+	}
+	console := append([]string{"Started by user goose"}, job.Console...)
+	hang := job.Hang
+	if len(job.FailWhen) > 0 && matches(params, job.FailWhen) {
+		result = gojenkins.RESULT_STATUS_FAILURE
+		console = console[:1]
+		hang = false
+	}
+	console = append(console, "Finished: "+result)
+
+	s.nextQueueID++
+	build := &Build{
+		Number:     nextBuildNumber(job),
+		QueueID:    s.nextQueueID,
+		Parameters: params,
+		Console:    console,
+		Result:     result,
+		Running:    true,
+		Started:    time.Now(),
+		hang:       hang,
+	}
+	job.Builds = append(job.Builds, build)
+	s.started[build.QueueID] = startedItem{team: t, job: job, path: path, number: build.Number}
+
+	w.Header().Set("Location", fmt.Sprintf("%squeue/item/%d/", s.teamURL(t), build.QueueID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// matches reports whether params has every value of want.
+func matches(params map[string]string, want map[string]string) bool {
+	for name, value := range want {
+		if params[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) serveBuild(w http.ResponseWriter, r *http.Request, t *Team, build *Build, path []string, segments []string) {
+	switch {
+	case isAPI(segments):
+		writeJSON(w, s.buildJSON(t, build, path))
+	case len(segments) == 1 && segments[0] == "consoleText":
+		fmt.Fprint(w, consoleText(build))
+	case len(segments) == 2 && segments[0] == "logText" && segments[1] == "progressiveText":
+		// Every poll prints one more line, the build ends after the last one
+		if build.Running && !(build.hang && build.printed == len(build.Console)-1) {
+			build.printed++
+			if build.printed >= len(build.Console) {
+				build.Running = false
+				build.Duration = time.Since(build.Started)
+			}
+		}
+		text := consoleText(build)
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		if start > len(text) {
+			start = len(text)
+		}
+		w.Header().Set("X-Text-Size", strconv.Itoa(len(text)))
+		if build.Running {
+			w.Header().Set("X-More-Data", "true")
+		}
+		fmt.Fprint(w, text[start:])
+	case len(segments) > 1 && segments[0] == "artifact":
+		content, ok := build.Artifacts[strings.Join(segments[1:], "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == http.MethodHead {
+			return
+		}
+		fmt.Fprint(w, content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		// Other requests go on while the download stalls
+		s.mu.Unlock()
+		time.Sleep(build.ArtifactDelay)
+		s.mu.Lock()
+		fmt.Fprint(w, content[len(content)/2:])
+	case len(segments) == 1 && (segments[0] == "stop" || segments[0] == "term" || segments[0] == "kill") && r.Method == http.MethodPost:
+		if build.Running {
+			build.Console = append(build.Console[:build.printed], "Aborted by goose", "Finished: ABORTED")
+			build.Result = gojenkins.STATUS_ABORTED
+			build.Running = false
+			build.Duration = time.Since(build.Started)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func consoleText(build *Build) string {
+	lines := build.Console
+	if build.Running {
+		lines = lines[:build.printed]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func nextBuildNumber(job *Job) int64 {
+	next := int64(1)
+	for _, build := range job.Builds {
+		if build.Number >= next {
+			next = build.Number + 1
+		}
+	}
+	return next
+}
+
+const (
+	folderClass   = "com.cloudbees.hudson.plugins.folder.Folder"
+	pipelineClass = "org.jenkinsci.plugins.workflow.job.WorkflowJob"
+)
+
+// teamURL is the url of a team's controller, ending with a slash.
+func (s *Server) teamURL(t *Team) string {
+	if t.Name == "" {
+		return s.URL + "/"
+	}
+	return fmt.Sprintf("%s/teams-%s/", s.URL, t.Name)
+}
+
+func (s *Server) jobURL(t *Team, path []string) string {
+	u := s.teamURL(t)
+	for _, name := range path {
+		u += "job/" + url.PathEscape(name) + "/"
+	}
+	return u
+}
+
+func (s *Server) innerJobs(t *Team, parent []string, jobs []*Job) []gojenkins.InnerJob {
+	inner := []gojenkins.InnerJob{}
+	for _, job := range jobs {
+		path := append(append([]string{}, parent...), job.Name)
+		class := pipelineClass
+		if len(job.Jobs) > 0 {
+			class = folderClass
+		}
+		inner = append(inner, gojenkins.InnerJob{Class: class, Name: job.Name, Url: s.jobURL(t, path), Color: color(job)})
+	}
+	return inner
+}
+
+func color(job *Job) string {
+	if len(job.Jobs) > 0 {
+		return ""
+	}
+	last := lastBuild(job, func(*Build) bool { return true })
+	switch {
+	case last == nil:
+		return "notbuilt"
+	case last.Running:
+		return "blue_anime"
+	case last.Result == gojenkins.STATUS_SUCCESS:
+		return "blue"
+	case last.Result == gojenkins.STATUS_ABORTED:
+		return "aborted"
+	default:
+		return "red"
+	}
+}
+
+func lastBuild(job *Job, matches func(*Build) bool) *Build {
+	var last *Build
+	for _, build := range job.Builds {
+		if matches(build) && (last == nil || build.Number > last.Number) {
+			last = build
+		}
+	}
+	return last
+}
+
+func (s *Server) jobJSON(t *Team, job *Job, path []string) map[string]interface{} {
+	jobURL := s.jobURL(t, path)
+	ref := func(build *Build) interface{} {
+		if build == nil {
+			return nil
+		}
+		return map[string]interface{}{"number": build.Number, "url": fmt.Sprintf("%s%d/", jobURL, build.Number)}
+	}
+	builds := []interface{}{}
+	for i := len(job.Builds) - 1; i >= 0; i-- {
+		builds = append(builds, ref(job.Builds[i]))
+	}
+	class := pipelineClass
+	if len(job.Jobs) > 0 {
+		class = folderClass
+	}
+	properties := []interface{}{}
+	if len(job.Params) > 0 {
+		properties = append(properties, map[string]interface{}{"parameterDefinitions": job.Params})
+	}
+	return map[string]interface{}{
+		"_class":              class,
+		"name":                job.Name,
+		"displayName":         job.Name,
+		"fullName":            strings.Join(path, "/"),
+		"fullDisplayName":     strings.Join(path, " » "),
+		"url":                 jobURL,
+		"color":               color(job),
+		"buildable":           len(job.Jobs) == 0,
+		"inQueue":             false,
+		"jobs":                s.innerJobs(t, path, job.Jobs),
+		"property":            properties,
+		"builds":              builds,
+		"nextBuildNumber":     nextBuildNumber(job),
+		"firstBuild":          ref(lastBuild(job, func(b *Build) bool { return b.Number == 1 })),
+		"lastBuild":           ref(lastBuild(job, func(*Build) bool { return true })),
+		"lastCompletedBuild":  ref(lastBuild(job, func(b *Build) bool { return !b.Running })),
+		"lastSuccessfulBuild": ref(lastBuild(job, func(b *Build) bool { return !b.Running && b.Result == gojenkins.STATUS_SUCCESS })),
+		"lastFailedBuild":     ref(lastBuild(job, func(b *Build) bool { return !b.Running && b.Result == gojenkins.RESULT_STATUS_FAILURE })),
+	}
+}
+
+func (s *Server) buildJSON(t *Team, build *Build, path []string) map[string]interface{} {
+	result := build.Result
+	duration := build.Duration
+	if build.Running {
+		result = ""
+		duration = 0
+	}
+	return map[string]interface{}{
+		"_class":          "org.jenkinsci.plugins.workflow.job.WorkflowRun",
+		"number":          build.Number,
+		"id":              strconv.FormatInt(build.Number, 10),
+		"url":             fmt.Sprintf("%s%d/", s.jobURL(t, path), build.Number),
+		"fullDisplayName": fmt.Sprintf("%s #%d", strings.Join(path, " » "), build.Number),
+		"building":        build.Running,
+		"result":          result,
+		"queueId":         build.QueueID,
+		"timestamp":       build.Started.UnixNano() / int64(time.Millisecond),
+		"duration":        duration.Milliseconds(),
+		"actions": []interface{}{
+			parametersAction(build.Parameters),
+			map[string]interface{}{"_class": "hudson.model.CauseAction", "causes": []interface{}{
+				map[string]string{"shortDescription": "Started by user goose", "userId": User},
+			}},
+		},
+		"artifacts":  artifactsJSON(build),
+		"changeSets": []interface{}{},
+	}
+}
+
+func artifactsJSON(build *Build) []map[string]string {
+	paths := []string{}
+	for relativePath := range build.Artifacts {
+		paths = append(paths, relativePath)
+	}
+	sort.Strings(paths)
+	artifacts := []map[string]string{}
+	for _, relativePath := range paths {
+		artifacts = append(artifacts, map[string]string{"fileName": path.Base(relativePath), "relativePath": relativePath})
+	}
+	return artifacts
+}
+
+func parametersAction(params map[string]string) map[string]interface{} {
+	names := []string{}
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := []map[string]string{}
+	for _, name := range names {
+		values = append(values, map[string]string{"name": name, "value": params[name]})
+	}
+	return map[string]interface{}{"_class": "hudson.model.ParametersAction", "parameters": values}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,200 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/prologic/bitcask"
+)
+
+// historyPath is the bitcask store holding every build goose started. It
+// sits next to the job index.
+var historyPath = storePath("jenkins-history")
+
+// historyLock serializes access to the store, bitcask only allows one open
+// handle and runlist records builds from many goroutines.
+var historyLock sync.Mutex
+
+// HistoryRecord is a build goose triggered.
+type HistoryRecord struct {
+	ID         string            `json:"id" yaml:"id"`
+	Job        string            `json:"job" yaml:"job"`
+	Repo       string            `json:"repo,omitempty" yaml:"repo,omitempty"`
+	Commit     string            `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Number     int64             `json:"number" yaml:"number"`
+	URL        string            `json:"url" yaml:"url"`
+	Result     string            `json:"result" yaml:"result"`
+	Started    time.Time         `json:"started" yaml:"started"`
+	Finished   time.Time         `json:"finished,omitempty" yaml:"finished,omitempty"`
+}
+
+// HistoryFilter narrows down ListHistory. Empty fields match everything.
+type HistoryFilter struct {
+	// Job and Repo match any record containing them.
+	Job  string
+	Repo string
+	// Result matches the build result, ignoring case.
+	Result string
+	Since  time.Time
+	Until  time.Time
+}
+
+func (f HistoryFilter) matches(record HistoryRecord) bool {
+	if f.Job != "" && !strings.Contains(record.Job, f.Job) {
+		return false
+	}
+	if f.Repo != "" && !strings.Contains(record.Repo, f.Repo) {
+		return false
+	}
+	if f.Result != "" && !strings.EqualFold(record.Result, f.Result) {
+		return false
+	}
+	if !f.Since.IsZero() && record.Started.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && record.Started.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func withHistory(f func(db *bitcask.Bitcask) error) error {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	db, err := openStore(historyPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return f(db)
+}
+
+// SaveHistory adds or replaces a record.
+func SaveHistory(record HistoryRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return withHistory(func(db *bitcask.Bitcask) error {
+		return db.Put([]byte(record.ID), value)
+	})
+}
+
+// GetHistory looks up a single record by its ID.
+func GetHistory(id string) (HistoryRecord, error) {
+	var record HistoryRecord
+	err := withHistory(func(db *bitcask.Bitcask) error {
+		value, err := db.Get([]byte(id))
+		if err != nil {
+			return fmt.Errorf("no build with id %s in history", id)
+		}
+		return json.Unmarshal(value, &record)
+	})
+	return record, err
+}
+
+// ListHistory returns the records matching filter, newest first.
+func ListHistory(filter HistoryFilter) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+	err := withHistory(func(db *bitcask.Bitcask) error {
+		return db.Fold(func(key []byte) error {
+			value, err := db.Get(key)
+			if err != nil {
+				return err
+			}
+			var record HistoryRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+			if filter.matches(record) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID > records[j].ID
+	})
+	return records, err
+}
+
+// HiddenValue is recorded instead of the values of password and file
+// parameters, which are never written to the history.
+const HiddenValue = "********"
+
+// historyParameters copies choices, hiding the job's password and file
+// parameters.
+func historyParameters(thejob *gojenkins.Job, choices map[string]string) map[string]string {
+	secret := secretParameters(thejob)
+	params := map[string]string{}
+	for name, value := range choices {
+		if secret[name] {
+			value = HiddenValue
+		}
+		params[name] = value
+	}
+	return params
+}
+
+// recordStart stores a build that just left the queue. Failing to write the
+// history never fails the build, it's only reported.
+func recordStart(thejob *gojenkins.Job, choices map[string]string, build *gojenkins.Build) HistoryRecord {
+	now := time.Now()
+	record := HistoryRecord{
+		ID:         fmt.Sprintf("%d", now.UnixNano()),
+		Job:        thejob.Raw.FullName,
+		Repo:       GetCurrentGitRepo(),
+		Commit:     GetCurrentCommit(),
+		Parameters: historyParameters(thejob, choices),
+		Number:     build.GetBuildNumber(),
+		URL:        build.GetUrl(),
+		Started:    now,
+	}
+	if err := SaveHistory(record); err != nil {
+		fmt.Println("Could not save build history:", err)
+	}
+	return record
+}
+
+// ResultDetached is recorded for builds goose stopped following before they
+// finished, without aborting them. Their real result is on jenkins.
+const ResultDetached = "DETACHED"
+
+// recordFinish stores the result of a build recorded with recordStart.
+func recordFinish(record HistoryRecord, result *BuildResult) {
+	record.Result = result.Result
+	record.Finished = time.Now()
+	if err := SaveHistory(record); err != nil {
+		fmt.Println("Could not save build history:", err)
+	}
+}
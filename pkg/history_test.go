@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goose-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	historyPath = filepath.Join(dir, "history")
+
+	day := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []HistoryRecord{
+		{ID: "1", Job: "Example/Folder/main", Repo: "github.com/example/folder.git", Result: "SUCCESS", Started: day},
+		{ID: "2", Job: "Example/Folder/main", Repo: "github.com/example/folder.git", Result: "FAILURE", Started: day.Add(24 * time.Hour)},
+		{ID: "3", Job: "Example/Other/main", Repo: "github.com/example/other.git", Result: "FAILURE", Started: day.Add(48 * time.Hour),
+			Parameters: map[string]string{"tenant": "a"}},
+	}
+	for _, record := range records {
+		if err := SaveHistory(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		want   []string
+	}{
+		{name: "TestAll", filter: HistoryFilter{}, want: []string{"3", "2", "1"}},
+		{name: "TestJob", filter: HistoryFilter{Job: "Folder"}, want: []string{"2", "1"}},
+		{name: "TestRepo", filter: HistoryFilter{Repo: "other"}, want: []string{"3"}},
+		{name: "TestResult", filter: HistoryFilter{Result: "failure"}, want: []string{"3", "2"}},
+		{name: "TestDates", filter: HistoryFilter{Since: day.Add(time.Hour), Until: day.Add(25 * time.Hour)}, want: []string{"2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListHistory(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, record := range got {
+				ids = append(ids, record.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("ListHistory() = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("ListHistory() = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+
+	record, err := GetHistory("3")
+	if err != nil || record.Parameters["tenant"] != "a" {
+		t.Errorf("GetHistory() = %+v, %v", record, err)
+	}
+	if _, err := GetHistory("4"); err == nil {
+		t.Errorf("GetHistory() of a missing id should fail")
+	}
+}
+
+func TestHistoryParameters(t *testing.T) {
+	job := &gojenkins.Job{Raw: &gojenkins.JobResponse{}}
+	job.Raw.Property = append(job.Raw.Property, struct {
+		ParameterDefinitions []gojenkins.ParameterDefinition `json:"parameterDefinitions"`
+	}{ParameterDefinitions: []gojenkins.ParameterDefinition{
+		{Name: "tenant", Type: ChoiceParameter},
+		{Name: "token", Type: PasswordParameter},
+		{Name: "manifest", Type: FileParameter},
+	}})
+
+	got := historyParameters(job, map[string]string{"tenant": "a", "token": "hunter2", "manifest": "./manifest.yaml"})
+	want := map[string]string{"tenant": "a", "token": HiddenValue, "manifest": HiddenValue}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("historyParameters() = %v, want %v", got, want)
+	}
+}
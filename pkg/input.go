@@ -0,0 +1,160 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// InputRequest is an input step a pipeline is paused on.
+type InputRequest struct {
+	ID          string
+	Message     string
+	ProceedText string
+	Parameters  []gojenkins.ParameterDefinition
+}
+
+// InputHandler answers an input step, e.g. with ProceedInput or AbortInput.
+type InputHandler func(ctx context.Context, B *gojenkins.Build, input InputRequest) error
+
+type wfapiInput struct {
+	ID          string `json:"id"`
+	Message     string `json:"message"`
+	ProceedText string `json:"proceedText"`
+	Inputs      []struct {
+		Type        string                 `json:"type"`
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		Definition  map[string]interface{} `json:"definition"`
+	} `json:"inputs"`
+}
+
+// GetPendingInputs lists the input steps a build is waiting on.
+func GetPendingInputs(ctx context.Context, B *gojenkins.Build) ([]InputRequest, error) {
+	raw := []wfapiInput{}
+	if err := getWfapi(ctx, B, "/wfapi/pendingInputActions", &raw); err != nil {
+		return nil, err
+	}
+	inputs := []InputRequest{}
+	for _, action := range raw {
+		input := InputRequest{ID: action.ID, Message: action.Message, ProceedText: action.ProceedText}
+		for _, in := range action.Inputs {
+			param := gojenkins.ParameterDefinition{Name: in.Name, Type: in.Type, Description: in.Description}
+			param.DefaultParameterValue.Value = inputDefault(in.Definition)
+			if choices, ok := in.Definition["choices"].([]interface{}); ok {
+				for _, choice := range choices {
+					param.Choices = append(param.Choices, fmt.Sprintf("%v", choice))
+				}
+			}
+			input.Parameters = append(input.Parameters, param)
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// inputDefault digs the default value out of an input parameter's
+// definition, which differs between parameter kinds.
+func inputDefault(definition map[string]interface{}) interface{} {
+	if value, ok := definition["defaultParameterValue"].(map[string]interface{}); ok {
+		return value["value"]
+	}
+	for _, key := range []string{"defaultValue", "defaultVal"} {
+		if value, ok := definition[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// HandleInputs hands every input step the build waits on to handle, once.
+// handled remembers the steps already handed over between calls.
+func HandleInputs(ctx context.Context, B *gojenkins.Build, handled map[string]bool, handle InputHandler) error {
+	inputs, err := GetPendingInputs(ctx, B)
+	if err != nil {
+		return err
+	}
+	for _, input := range inputs {
+		if handled[input.ID] {
+			continue
+		}
+		handled[input.ID] = true
+		if err := handle(ctx, B, input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProceedInput lets a paused pipeline continue, answering the input step's
+// parameters with values.
+func ProceedInput(ctx context.Context, B *gojenkins.Build, input InputRequest, values map[string]string) error {
+	if len(input.Parameters) == 0 {
+		return postInput(ctx, B, input, "proceedEmpty", nil)
+	}
+
+	type parameter struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	submitted := struct {
+		Parameter []parameter `json:"parameter"`
+	}{}
+	for _, param := range input.Parameters {
+		submitted.Parameter = append(submitted.Parameter, parameter{Name: param.Name, Value: values[param.Name]})
+	}
+	encoded, err := json.Marshal(submitted)
+	if err != nil {
+		return err
+	}
+	data := url.Values{}
+	data.Set("json", string(encoded))
+	data.Set("proceed", input.ProceedText)
+	return postInput(ctx, B, input, "submit", data)
+}
+
+// AbortInput aborts the pipeline at an input step.
+func AbortInput(ctx context.Context, B *gojenkins.Build, input InputRequest) error {
+	return postInput(ctx, B, input, "abort", nil)
+}
+
+func postInput(ctx context.Context, B *gojenkins.Build, input InputRequest, action string, data url.Values) error {
+	endpoint := fmt.Sprintf("%s/input/%s/%s", B.Base, input.ID, action)
+	resp, err := B.Jenkins.Requester.Post(ctx, endpoint, bytes.NewBufferString(data.Encode()), nil, nil)
+	if err != nil {
+		return errors.Wrapf(err, "could not %s input %s", action, input.ID)
+	}
+	if resp.StatusCode >= 400 {
+		return errors.Errorf("could not %s input %s: %s", action, input.ID, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestInputs(t *testing.T) {
+	var lock sync.Mutex
+	posts := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		switch {
+		case path == "/job/Example/1/wfapi/pendingInputActions":
+			fmt.Fprint(w, `[
+				{"id": "Prod", "message": "Proceed to prod?", "proceedText": "Ship it", "inputs": [
+					{"type": "ChoiceParameterDefinition", "name": "region", "description": "where",
+					 "definition": {"choices": ["us", "eu"], "defaultParameterValue": {"value": "us"}}},
+					{"type": "BooleanParameterDefinition", "name": "canary", "definition": {"defaultVal": true}}
+				]},
+				{"id": "Cleanup", "message": "Clean up?"}
+			]`)
+		case r.Method == "POST" && strings.HasPrefix(path, "/job/Example/1/input/"):
+			r.ParseForm()
+			posts[strings.TrimPrefix(path, "/job/Example/1/input/")] = r.PostForm.Get("json")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	build := &gojenkins.Build{Jenkins: gojenkins.CreateJenkins(server.Client(), server.URL), Base: "/job/Example/1", Raw: &gojenkins.BuildResponse{}}
+	inputs, err := GetPendingInputs(context.Background(), build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 2 || inputs[0].ProceedText != "Ship it" || len(inputs[0].Parameters) != 2 {
+		t.Fatalf("GetPendingInputs() = %+v", inputs)
+	}
+	region, canary := inputs[0].Parameters[0], inputs[0].Parameters[1]
+	if !reflect.DeepEqual(region.Choices, []string{"us", "eu"}) || ParameterDefault(region) != "us" || ParameterDefault(canary) != "true" {
+		t.Errorf("GetPendingInputs() parameters = %+v", inputs[0].Parameters)
+	}
+
+	handled := map[string]bool{}
+	answer := func(ctx context.Context, B *gojenkins.Build, input InputRequest) error {
+		if input.ID == "Prod" {
+			return ProceedInput(ctx, B, input, map[string]string{"region": "eu", "canary": "false"})
+		}
+		return AbortInput(ctx, B, input)
+	}
+	for i := 0; i < 2; i++ {
+		if err := HandleInputs(context.Background(), build, handled, answer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(posts) != 2 {
+		t.Fatalf("HandleInputs() posted %v, want each input answered once", posts)
+	}
+	submitted := struct {
+		Parameter []map[string]string `json:"parameter"`
+	}{}
+	if err := json.Unmarshal([]byte(posts["Prod/submit"]), &submitted); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{{"name": "region", "value": "eu"}, {"name": "canary", "value": "false"}}
+	if !reflect.DeepEqual(submitted.Parameter, want) {
+		t.Errorf("ProceedInput() submitted %v, want %v", submitted.Parameter, want)
+	}
+	if _, ok := posts["Cleanup/abort"]; !ok {
+		t.Errorf("AbortInput() was not posted: %v", posts)
+	}
+}
@@ -30,12 +30,10 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -52,77 +50,259 @@ func correctSlash(path string) string {
 	return newstring
 }
 
-func FollowBuild(B *gojenkins.Build) {
-	re := NewRegexpWriter(os.Stdout)
+// FollowBuild streams the console of a build to out until it finishes,
+// leaving out the [Pipeline] and git noise. It returns early with the
+// context's error when ctx is cancelled.
+func FollowBuild(ctx context.Context, client Client, B *gojenkins.Build, out io.Writer) error {
+	return FollowBuildWithInputs(ctx, client, B, out, nil)
+}
+
+// FollowBuildWithInputs is FollowBuild, also handing every input step the
+// pipeline pauses on to handle when it is set. The console isn't read while
+// handle runs.
+func FollowBuildWithInputs(ctx context.Context, client Client, B *gojenkins.Build, out io.Writer, handle InputHandler) error {
+	re := NewRegexpWriter(out)
 	red := New(Brown, Black)
 	re.AddRule(red, regexp.MustCompile(`\[Pipeline\]`))
+	var between func() error
+	if handle != nil {
+		handled := map[string]bool{}
+		watching := true
+		between = func() error {
+			if !watching {
+				return nil
+			}
+			if err := HandleInputs(ctx, B, handled, handle); err != nil {
+				// Not a pipeline or the step couldn't be answered, the console goes on
+				fmt.Fprintln(out, "Not watching for input steps anymore:", err)
+				watching = false
+			}
+			return nil
+		}
+	}
+	return pollConsole(ctx, client, B, func(content string) {
+		lines := strings.Split(content, "\n")
+		for _, line := range lines {
+			if !strings.Contains(line, "[Pipeline]") && !strings.Contains(line, "> git") {
+				re.WriteString(line + "\n")
+			}
+		}
+	}, between)
+}
+
+// SaveConsole copies the unfiltered console of a build to out until it
+// finishes.
+func SaveConsole(ctx context.Context, client Client, B *gojenkins.Build, out io.Writer) error {
+	return pollConsole(ctx, client, B, func(content string) {
+		io.WriteString(out, content)
+	}, nil)
+}
+
+// pollConsole hands every new piece of the console, read through client, to
+// handle until the build stops, including whatever was written after the
+// last poll. between, when set, is called after each poll while the build
+// runs.
+func pollConsole(ctx context.Context, client Client, B *gojenkins.Build, handle func(string), between func() error) error {
 	offset := int64(0)
-	for B.IsRunning(context.TODO()) {
-		time.Sleep(time.Second * 2)
-		resp, _ := B.GetConsoleOutputFromIndex(context.TODO(), offset)
-		offset = resp.Offset
-		if len(resp.Content) > 0 {
-			lines := strings.Split(resp.Content, "\n")
-			for _, line := range lines {
-				if !strings.Contains(line, "[Pipeline]") && !strings.Contains(line, "> git") {
-					re.WriteString(line + "\n")
-				}
+	for {
+		running := B.IsRunning(ctx)
+		for {
+			chunk, err := client.GetConsole(ctx, B, offset)
+			if err != nil || chunk.Offset == offset {
+				break
+			}
+			offset = chunk.Offset
+			if len(chunk.Text) > 0 {
+				handle(chunk.Text)
 			}
+			if !chunk.More {
+				break
+			}
+		}
+		if !running {
+			return ctx.Err()
+		}
+		if between != nil {
+			if err := between(); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * 2):
 		}
 	}
 }
 
-func GetJobParameters(J *gojenkins.Jenkins, path string) map[string]gojenkins.ParameterDefinition {
-	nj, err := GetNestedJob(J, path)
+// GetJobParameters returns the parameters of a job in the order they are
+// declared.
+func GetJobParameters(client Client, path string) []gojenkins.ParameterDefinition {
+	params, err := client.GetParameters(context.TODO(), path)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if nj != nil {
-		params, _ := nj.GetParameters(context.TODO())
-		paramlist := map[string]gojenkins.ParameterDefinition{}
-		for _, param := range params {
-			paramlist[param.Name] = param
+	return params
+}
+
+// BuildResult is the outcome of a build started by goose.
+type BuildResult struct {
+	Job        string
+	QueueID    int64
+	Number     int64
+	URL        string
+	Result     string
+	Started    time.Time
+	Duration   time.Duration
+	Parameters map[string]string
+}
+
+// Succeeded reports whether the build finished with SUCCESS.
+func (r *BuildResult) Succeeded() bool {
+	return r.Result == gojenkins.STATUS_SUCCESS
+}
+
+// NewBuildResult summarizes a build. Result is empty while it is running.
+func NewBuildResult(B *gojenkins.Build) *BuildResult {
+	job := ""
+	if B.Job != nil {
+		job = B.Job.Raw.FullName
+	}
+	params := map[string]string{}
+	for _, param := range B.GetParameters() {
+		params[param.Name] = param.Value
+	}
+	return &BuildResult{
+		Job:        job,
+		QueueID:    B.Raw.QueueID,
+		Number:     B.GetBuildNumber(),
+		URL:        B.GetUrl(),
+		Result:     B.GetResult(),
+		Started:    B.GetTimestamp(),
+		Duration:   time.Duration(B.GetDuration()) * time.Millisecond,
+		Parameters: params,
+	}
+}
+
+// InvokeJob triggers a build, follows its console until it finishes and
+// returns how it went. A failed build is not an error, check
+// BuildResult.Succeeded. The build is saved to the history.
+func InvokeJob(ctx context.Context, client Client, thejob *gojenkins.Job, choices map[string]string) (*BuildResult, error) {
+	return InvokeJobWith(ctx, client, thejob, choices, func(ctx context.Context, B *gojenkins.Build) error {
+		return FollowBuild(ctx, client, B, os.Stdout)
+	})
+}
+
+// FollowFunc shows a running build until it stops.
+type FollowFunc func(ctx context.Context, B *gojenkins.Build) error
+
+// InvokeJobWith is InvokeJob with another way of following the build than
+// printing its console.
+func InvokeJobWith(ctx context.Context, client Client, thejob *gojenkins.Job, choices map[string]string, follow FollowFunc) (*BuildResult, error) {
+	build, err := StartJob(ctx, client, thejob, choices, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	record := recordStart(thejob, choices, build)
+	if err := follow(ctx, build); err != nil {
+		return NewBuildResult(build), err
+	}
+	result, err := WaitForResult(ctx, build)
+	if err != nil {
+		return result, err
+	}
+	recordFinish(record, result)
+	return result, nil
+}
+
+// WaitForResult polls a build until Jenkins reports its final result.
+func WaitForResult(ctx context.Context, B *gojenkins.Build) (*BuildResult, error) {
+	for {
+		if _, err := B.Poll(ctx); err != nil {
+			return nil, errors.Wrapf(err, "could not get the result of %s", B.GetUrl())
+		}
+		if !B.Raw.Building && B.GetResult() != "" {
+			return NewBuildResult(B), nil
+		}
+		select {
+		case <-ctx.Done():
+			return NewBuildResult(B), ctx.Err()
+		case <-time.After(time.Second):
 		}
-		return paramlist
 	}
-	fmt.Println(fmt.Sprintf("%s not found", path))
-	return nil
 }
 
-func InvokeJob(Jenky *gojenkins.Jenkins, thejob *gojenkins.Job, choices map[string]string) {
-	queueNum, _ := thejob.InvokeSimple(context.TODO(), choices)
+// StartJob triggers a build and waits for it to leave the queue. Progress is
+// shown with a spinner on stdout and as plain lines on any other writer. If
+// ctx is cancelled while the build is still queued the queue item is
+// cancelled. Values of file parameters are paths of local files to upload.
+func StartJob(ctx context.Context, client Client, thejob *gojenkins.Job, choices map[string]string, out io.Writer) (*gojenkins.Build, error) {
+	queueNum, err := client.Invoke(ctx, thejob, choices)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not invoke %s", thejob.GetName())
+	}
+	if queueNum == 0 {
+		return nil, errors.Errorf("%s is already queued", thejob.GetName())
+	}
 
-	var build *gojenkins.Build
-	spinner := wow.New(os.Stdout, spin.Get(spin.Dots), "Waiting to start build...")
-	t, _ := Jenky.GetQueueItem(context.TODO(), queueNum)
+	t, err := client.GetQueueItem(ctx, client.Team(thejob.Raw.FullName), queueNum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find queue item %d", queueNum)
+	}
 
-	spinner.Start() // MAKE SURE TO STOP THIS!!
+	var spinner *wow.Wow
+	if out == os.Stdout {
+		spinner = wow.New(out, spin.Get(spin.Dots), "Waiting to start build...")
+		spinner.Start() // MAKE SURE TO STOP THIS!!
+		defer spinner.Stop()
+	} else {
+		fmt.Fprintln(out, "Waiting to start build...")
+	}
+	why := ""
 	for {
+		// Show why jenkins hasn't started the build yet
+		if t.Raw.Why != why && t.Raw.Why != "" {
+			why = t.Raw.Why
+			if spinner != nil {
+				spinner.Text(strings.SplitN(why, "\n", 2)[0])
+			} else {
+				fmt.Fprintln(out, why)
+			}
+		}
 		if t.Raw.Executable.Number != 0 {
-			spinner.PersistWith(spin.Spinner{Frames: []string{"✅   "}}, fmt.Sprintf("Started as build %d", t.Raw.Executable.Number))
+			runningBuild, _ := thejob.GetBuild(ctx, t.Raw.Executable.Number)
+			if runningBuild != nil {
+				started := fmt.Sprintf("Started as build %d", t.Raw.Executable.Number)
+				if spinner != nil {
+					spinner.PersistWith(spin.Spinner{Frames: []string{"✅"}}, started)
+				} else {
+					fmt.Fprintln(out, started)
+				}
+				return runningBuild, nil
+			}
 		}
-		runningBuild, _ := thejob.GetBuild(context.Background(), t.Raw.Executable.Number)
-		if runningBuild != nil {
-			spinner.PersistWith(spin.Spinner{Frames: []string{"✅"}}, fmt.Sprintf("Getting logs for %d", t.Raw.Executable.Number))
-			build = runningBuild
-			break
+		select {
+		case <-ctx.Done():
+			t.Cancel(context.Background())
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
 		}
-		time.Sleep(2 * time.Second)
-		t.Poll(context.TODO())
+		t.Poll(ctx)
 	}
-	spinner.Stop()
-	FollowBuild(build)
 }
 
-func GetNestedJob(J *gojenkins.Jenkins, path string) (*gojenkins.Job, error) {
+// GetNestedJob finds the job at path on the controller the topology sends
+// it to. It leaves J alone, so it can be called from several goroutines at
+// once.
+func GetNestedJob(J *gojenkins.Jenkins, topology Topology, path string) (*gojenkins.Job, error) {
 	var currentJob *gojenkins.Job
 	path = correctSlash(path)
 	pathsplit := strings.Split(path, "/")
-	changeTeamURL(J, pathsplit[0])
+	team := topology.View(J, topology.Team(path))
 	for _, p := range pathsplit {
 		fixedString := correctSlash(p)
 		if currentJob == nil {
-			currentJob, _ = J.GetJob(context.TODO(), fixedString)
+			currentJob, _ = team.GetJob(context.TODO(), fixedString)
 		} else {
 			currentJob, _ = currentJob.GetInnerJob(context.TODO(), fixedString)
 		}
@@ -143,59 +323,52 @@ func isNumeric(s string) bool {
 	return err == nil
 }
 
-func GetBuild(J *gojenkins.Jenkins, path string, num int64) *gojenkins.Build {
-	j, err := GetNestedJob(J, path)
-	if err != nil {
-		log.Fatal(err)
+// ParseBuildPath reads the job and number of a build from its url or the
+// path of its url.
+func (t Topology) ParseBuildPath(url string) Build {
+	job, rest := t.JobPath(url)
+	build := Build{Job: job}
+	if len(rest) > 0 {
+		build.BuildNumber, _ = strconv.ParseInt(rest[0], 10, 64)
 	}
-	testB, _ := j.GetBuild(context.TODO(), num)
-	return testB
+	return build
 }
 
-func ParseBuildPath(url string) Build {
-	url = strings.Trim(url, "/")
-	m := strings.Replace(url, "/job/", "/", -1)
-	cleanedURL := strings.Split(m, "/")
-	// The first part has the team name
-	cleanedURL[0] = strings.Split(cleanedURL[0], "-")[1]
-
-	// Then remove the last part
-	buildnum, _ := strconv.ParseInt(cleanedURL[len(cleanedURL)-1], 10, 64)
-	cleanedURL = cleanedURL[:len(cleanedURL)-1]
-
-	// We dont need the beginning either
-	cleanedURL = cleanedURL[1:]
-
-	fullpath := ""
-	for num, ele := range cleanedURL {
-		fullpath += ele
-		if num < len(cleanedURL)-1 {
-			fullpath += "/"
+// ParseBuildRef reads a build given either as a build URL
+// (https://ci/teams-team/job/Folder/job/branch/12) or as job#number.
+func (t Topology) ParseBuildRef(ref string) (Build, error) {
+	if i := strings.LastIndex(ref, "#"); i > 0 && !strings.Contains(ref, "://") {
+		num, err := strconv.ParseInt(ref[i+1:], 10, 64)
+		if err != nil {
+			return Build{}, errors.Errorf("%q is not a build number", ref[i+1:])
 		}
+		return Build{Job: ref[:i], BuildNumber: num}, nil
 	}
 
-	return Build{BuildNumber: buildnum, Job: fullpath}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return Build{}, errors.Wrapf(err, "could not parse %s", ref)
+	}
+	if !strings.Contains(u.EscapedPath(), "/job/") {
+		return Build{}, errors.Errorf("%s is neither a build URL nor job#number", ref)
+	}
+	build := t.ParseBuildPath(ref)
+	if build.BuildNumber == 0 || build.Job == "" {
+		return Build{}, errors.Errorf("%s does not point at a build", ref)
+	}
+	return build, nil
 }
 
-func BuildScriptRequest(J *gojenkins.Jenkins, team string, script string) *http.Request {
-	changeTeamURL(J, team)
-	data := url.Values{}
-	data.Set("script", script)
-
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/scriptText", J.Server), bytes.NewBufferString(data.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; param=value")
-	req.SetBasicAuth(J.Requester.BasicAuth.Username, J.Requester.BasicAuth.Password)
-
-	return req
-}
+// ErrScriptDenied is the cause of RunScript's error when jenkins refuses the
+// script console to the user.
+var ErrScriptDenied = errors.New("the script console needs the Overall/Administer permission")
 
-func RunScript(J *gojenkins.Jenkins, team string, script string) (string, error) {
-	changeTeamURL(J, team)
+func RunScript(J *gojenkins.Jenkins, topology Topology, team string, script string) (string, error) {
 	data := url.Values{}
 	data.Set("script", script)
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/scriptText", J.Server), bytes.NewBufferString(data.Encode()))
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/scriptText", topology.ControllerURL(team)), bytes.NewBufferString(data.Encode()))
 
 	if err != nil {
 		return "nil", err
@@ -206,39 +379,25 @@ func RunScript(J *gojenkins.Jenkins, team string, script string) (string, error)
 	if err != nil {
 		return "nil", err
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", errors.Wrapf(ErrScriptDenied, "running a script on %s failed with %s", team, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("running a script on %s failed with %s", team, resp.Status)
+	}
 
 	text, _ := ioutil.ReadAll(resp.Body)
 
 	return string(text), err
 }
 
-func GetInnerJobs(J *gojenkins.Jenkins, job *gojenkins.Job) []*gojenkins.Job {
-	jobs, err := job.GetInnerJobs(context.TODO())
-	if err != nil {
-		return nil
-	}
-	return jobs
-}
-
-func changeTeamURL(J *gojenkins.Jenkins, team string) {
-	rootJenkins := os.Getenv("JENKINS_ROOT_URL")
-	J.Requester.Base = fmt.Sprintf("%s/teams-%s", rootJenkins, team)
-	J.Server = fmt.Sprintf("%s/teams-%s", rootJenkins, team)
-}
-
-func GetJobs(J *gojenkins.Jenkins) []*gojenkins.Job {
-	var joblist []*gojenkins.Job
-	jobs, err := J.GetAllJobs(context.TODO())
-	if err != nil {
-		return nil
-	}
-
-	for _, job := range jobs {
-		ljob := job
-		joblist = append(joblist, ljob)
-	}
-
-	return joblist
+// viewAt copies J to talk to the jenkins at base instead. The copy shares
+// J's credentials and http client.
+func viewAt(J *gojenkins.Jenkins, base string) *gojenkins.Jenkins {
+	requester := *J.Requester
+	requester.Base = base
+	return &gojenkins.Jenkins{Server: base, Version: J.Version, Raw: J.Raw, Requester: &requester}
 }
 
 func getLinks(body io.Reader) []string {
@@ -265,87 +424,3 @@ func getLinks(body io.Reader) []string {
 		}
 	}
 }
-
-type result struct {
-	index int
-	Res   http.Response
-	err   error
-	URL   string
-}
-
-// boundedParallelGet sends requests in parallel but only up to a certain
-// limit, and furthermore it's only parallel up to the amount of CPUs but
-// is always concurrent up to the concurrency limit
-func BatchScript(requests []*http.Request, concurrencyLimit int) []result {
-
-	// this buffered channel will block at the concurrency limit
-	semaphoreChan := make(chan struct{}, concurrencyLimit)
-
-	// this channel will not block and collect the http request results
-	resultsChan := make(chan *result)
-
-	// make sure we close these channels when we're done with them
-	defer func() {
-		close(semaphoreChan)
-		close(resultsChan)
-	}()
-	client := &http.Client{}
-	// keen an index and loop through every url we will send a request to
-	for i, req := range requests {
-
-		// start a go routine with the index and url in a closure
-		rand.Seed(time.Now().UnixNano())
-		r := rand.Intn(100)
-		go func(i int, url *http.Request) {
-
-			// this sends an empty struct into the semaphoreChan which
-			// is basically saying add one to the limit, but when the
-			// limit has been reached block until there is room
-			semaphoreChan <- struct{}{}
-
-			// send the request and put the response in a result struct
-			// along with the index so we can sort them later along with
-			// any error that might have occoured
-
-			d := time.Duration(r) * time.Millisecond
-			time.Sleep(d)
-			res, err := client.Do(url)
-			if *&res.StatusCode == 400 {
-				fmt.Println("Failed... :(")
-			}
-			result := &result{i, *res, err, url.URL.RequestURI()}
-
-			// now we can send the result struct through the resultsChan
-			resultsChan <- result
-
-			// once we're done it's we read from the semaphoreChan which
-			// has the effect of removing one from the limit and allowing
-			// another goroutine to start
-			<-semaphoreChan
-
-		}(i, req)
-	}
-
-	// make a slice to hold the results we're expecting
-	var results []result
-
-	// start listening for any results over the resultsChan
-	// once we get a result append it to the result slice
-	for {
-		result := <-resultsChan
-		results = append(results, *result)
-
-		// if we've reached the expected amount of urls then stop
-		if len(results) == len(requests) {
-			break
-		}
-	}
-
-	// let's sort these results real quick
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].index < results[j].index
-	})
-
-	// now we're done we return the results
-	return results
-}
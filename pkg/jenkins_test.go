@@ -0,0 +1,241 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg/fakejenkins"
+)
+
+// startFakeJenkins serves a team "example" with the app's branches and a
+// team "other", points the JENKINS_* variables, the job index and the
+// history at it, and returns a Client logged in to it. Call the returned
+// func when done.
+func startFakeJenkins(t *testing.T) (*fakejenkins.Server, *JenkinsClient, func()) {
+	dir, err := ioutil.TempDir("", "goose-fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldIndex, oldHistory := jobIndexPath, historyPath
+	jobIndexPath = filepath.Join(dir, "index")
+	historyPath = filepath.Join(dir, "history")
+
+	envParam := gojenkins.ParameterDefinition{Name: "env", Type: ChoiceParameter, Choices: []string{"staging", "prod"}}
+	envParam.DefaultParameterValue.Value = "staging"
+	server := fakejenkins.New(
+		&fakejenkins.Team{Name: "example", Jobs: []*fakejenkins.Job{{Name: "example", Jobs: []*fakejenkins.Job{
+			{Name: "app", Jobs: []*fakejenkins.Job{
+				{Name: "master", Repo: "https://github.com/example/app.git", Params: []gojenkins.ParameterDefinition{envParam}, Console: []string{"deploying"},
+					Builds: []*fakejenkins.Build{{Number: 1, Result: "FAILURE", Console: []string{"boom"}}}},
+				{Name: "feature%2Flogin", Repo: "https://github.com/example/app.git", Result: "FAILURE"},
+			}},
+		}}}},
+		&fakejenkins.Team{Name: "other", Jobs: []*fakejenkins.Job{{Name: "other"}}},
+	)
+	env := map[string]string{}
+	for name, value := range server.Env() {
+		env[name] = os.Getenv(name)
+		os.Setenv(name, value)
+	}
+	J, err := gojenkins.CreateJenkins(nil, server.LoginURL(), fakejenkins.User, fakejenkins.Token).Init(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, NewJenkinsClient(J, Topology{Root: server.URL, Login: server.LoginURL()}), func() {
+		server.Close()
+		for name, value := range env {
+			os.Setenv(name, value)
+		}
+		jobIndexPath, historyPath = oldIndex, oldHistory
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGetNestedJob(t *testing.T) {
+	_, client, stop := startFakeJenkins(t)
+	defer stop()
+
+	tests := []struct {
+		name     string
+		path     string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "TestBranch", path: "example/app/master", wantName: "master"},
+		{name: "TestFolder", path: "example/app", wantName: "app"},
+		{name: "TestSlashInBranch", path: "example/app/feature//login", wantName: "feature%2Flogin"},
+		{name: "TestMissing", path: "example/app/nope", wantErr: true},
+		{name: "TestMissingTeam", path: "nope/app/master", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := GetNestedJob(client.J, client.Topology, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetNestedJob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && job.GetName() != tt.wantName {
+				t.Errorf("GetNestedJob() = %s, want %s", job.GetName(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestTeamViewsConcurrently(t *testing.T) {
+	_, client, stop := startFakeJenkins(t)
+	defer stop()
+	login := client.J.Server
+
+	paths := []string{"example/app/master", "other", "example/app", "other"}
+	errs := make(chan error, 10*len(paths))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, path := range paths {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				job, err := client.GetJob(context.Background(), path)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if job.Raw.FullName != path {
+					errs <- fmt.Errorf("GetJob(%s) found %s", path, job.Raw.FullName)
+				}
+			}(path)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if client.J.Server != login || client.J.Requester.Base != login {
+		t.Errorf("the client was moved to %s", client.J.Requester.Base)
+	}
+}
+
+func TestInvokeJob(t *testing.T) {
+	server, client, stop := startFakeJenkins(t)
+	defer stop()
+
+	job, err := client.GetJob(context.Background(), "example/app/master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := InvokeJob(context.Background(), client, job, map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Succeeded() || result.Number != 2 || result.Parameters["env"] != "prod" {
+		t.Errorf("InvokeJob() = %+v", result)
+	}
+	builds := server.Builds("example", "example/app/master")
+	if len(builds) != 2 || builds[1].Running || builds[1].Parameters["env"] != "prod" {
+		t.Errorf("builds on jenkins = %+v", builds)
+	}
+
+	records, err := ListHistory(HistoryFilter{})
+	if err != nil || len(records) != 1 || records[0].Result != "SUCCESS" || records[0].Number != 2 {
+		t.Errorf("ListHistory() = %+v, %v", records, err)
+	}
+
+	failing, err := client.GetJob(context.Background(), "example/app/feature//login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = InvokeJob(context.Background(), client, failing, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded() || result.Result != "FAILURE" {
+		t.Errorf("InvokeJob() of a failing job = %+v", result)
+	}
+}
+
+// upperConsoleClient shouts every console it reads.
+type upperConsoleClient struct {
+	*JenkinsClient
+}
+
+func (c upperConsoleClient) GetConsole(ctx context.Context, build *gojenkins.Build, offset int64) (ConsoleChunk, error) {
+	chunk, err := c.JenkinsClient.GetConsole(ctx, build, offset)
+	chunk.Text = strings.ToUpper(chunk.Text)
+	return chunk, err
+}
+
+func TestSaveConsoleThroughClient(t *testing.T) {
+	_, client, stop := startFakeJenkins(t)
+	defer stop()
+
+	build, err := client.GetBuild(context.Background(), "example/app/master", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var console strings.Builder
+	if err := SaveConsole(context.Background(), upperConsoleClient{client}, build, &console); err != nil {
+		t.Fatal(err)
+	}
+	if console.String() != "BOOM\n" {
+		t.Errorf("SaveConsole() = %q, want the console read through the client", console.String())
+	}
+}
+
+func TestParseBuildRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    Build
+		wantErr bool
+	}{
+		{
+			name: "TestURL",
+			ref:  "https://ci.example.com/teams-example/job/Example/job/Folder/job/mainbranch/12/",
+			want: Build{Job: "Example/Folder/mainbranch", BuildNumber: 12},
+		},
+		{
+			name: "TestEscapedBranch",
+			ref:  "https://ci.example.com/teams-example/job/Example/job/Folder/job/feature%2Fthing/3",
+			want: Build{Job: "Example/Folder/feature%2Fthing", BuildNumber: 3},
+		},
+		{
+			name: "TestJobNumber",
+			ref:  "Example/Folder/mainbranch#42",
+			want: Build{Job: "Example/Folder/mainbranch", BuildNumber: 42},
+		},
+		{
+			name:    "TestBadNumber",
+			ref:     "Example/Folder/mainbranch#last",
+			wantErr: true,
+		},
+		{
+			name:    "TestNotABuild",
+			ref:     "https://ci.example.com/teams-example/job/Example/",
+			wantErr: true,
+		},
+		{
+			name:    "TestJobOnly",
+			ref:     "Example/Folder/mainbranch",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Topology{Root: "https://ci.example.com"}.ParseBuildRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBuildRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBuildRef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
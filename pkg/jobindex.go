@@ -26,15 +26,13 @@ package pkg
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/kireledan/gojenkins"
 	"github.com/prologic/bitcask"
 )
 
@@ -62,12 +60,66 @@ Jenkins.instance.getAllItems(Job.class).each {
 
 const datadelimiter = ","
 
+// The job index and the history are kept in storeDir, one pair for every
+// jenkins goose talks to, told apart by storeName.
+var (
+	storeDir  = defaultStoreDir()
+	storeName = ""
+)
+
+// defaultStoreDir is goose's directory in the user's config directory, or
+// ~/.goose when there is none.
+func defaultStoreDir() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "goose")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".goose")
+	}
+	return filepath.Join(os.TempDir(), "goose")
+}
+
+// openStore opens the bitcask store at path. The history holds build
+// parameters, so only the user may read the stores.
+func openStore(path string) (*bitcask.Bitcask, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return bitcask.Open(path, bitcask.WithDirFileModeBeforeUmask(0700), bitcask.WithFileFileModeBeforeUmask(0600))
+}
+
+// jobIndexPath is the bitcask store mapping git repos to the jobs building
+// them.
+var jobIndexPath = storePath("jenkins-db")
+
+// SetStoreDir keeps the job index and the history in dir instead of the
+// user's config directory.
+func SetStoreDir(dir string) {
+	storeDir = dir
+	jobIndexPath, historyPath = storePath("jenkins-db"), storePath("jenkins-history")
+}
+
+// UseStore picks the job index and the history of one jenkins, named by
+// key, e.g. its context or root url, so jobs and builds of different
+// jenkins don't mix. An empty key picks the stores shared by all of them.
+func UseStore(key string) {
+	storeName = strings.Trim(unsafeFileChars.ReplaceAllString(key, "_"), "_")
+	jobIndexPath, historyPath = storePath("jenkins-db"), storePath("jenkins-history")
+}
+
+func storePath(name string) string {
+	if storeName != "" {
+		name += "-" + storeName
+	}
+	return filepath.Join(storeDir, name)
+}
+
 type JobIndex struct {
 	db *bitcask.Bitcask
 }
 
 func openDB() JobIndex {
-	db, _ := bitcask.Open("/tmp/jenkins-db")
+	db, _ := openStore(jobIndexPath)
 	return JobIndex{db}
 }
 
@@ -75,12 +127,12 @@ func (j JobIndex) closeDB() {
 	j.db.Close()
 }
 
-func RefreshJobIndex(j *gojenkins.Jenkins) {
+func RefreshJobIndex(client Client) {
 	db := openDB()
 	if db.db.Len() == 0 {
 		db.closeDB()
 		fmt.Println("Job index empty. Updating...")
-		BuildJobIndex(j)
+		BuildJobIndex(client)
 	} else {
 		db.closeDB()
 	}
@@ -166,6 +218,36 @@ func GetCurrentBranch() string {
 	return fixed
 }
 
+// GetCurrentRepoRoot returns the top directory of the git repo the working
+// directory is in, or "" outside of one.
+func GetCurrentRepoRoot() string {
+	dir, _ := os.Getwd()
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return ""
+	}
+	tree, err := repo.Worktree()
+	if err != nil {
+		return ""
+	}
+	return tree.Filesystem.Root()
+}
+
+// GetCurrentCommit returns the HEAD commit of the git repo in the working
+// directory, or "" outside of one.
+func GetCurrentCommit() string {
+	dir, _ := os.Getwd()
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return ""
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+	return head.Hash().String()
+}
+
 func (j JobIndex) addAffiliatedJob(url string, jenkinsJob string) {
 	if !j.isAlreadyAssociated(url, jenkinsJob) {
 		res, _ := j.db.Get([]byte(url))
@@ -193,25 +275,27 @@ func (j JobIndex) isAlreadyAssociated(url string, jenkinsJob string) bool {
 	return false
 }
 
-func BuildJobIndex(Jenky *gojenkins.Jenkins) {
-	//JobRepoMap := map[string][]string{}
-	jobs, _ := Jenky.GetAllJobNames(context.TODO())
-	requests := []*http.Request{}
-
-	for _, job := range jobs {
-		req := BuildScriptRequest(Jenky, job.Name, Query)
-		requests = append(requests, req)
+func BuildJobIndex(client Client) {
+	teams, err := client.Teams(context.TODO())
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-
-	//fmt.Println(requests)
+	outputs := make([]string, len(teams))
+	boundedParallel(len(teams), 30, func(i int) error {
+		output, err := client.RunScript(context.TODO(), teams[i], Query)
+		if err != nil {
+			fmt.Println("Failed... :(")
+		}
+		outputs[i] = output
+		return err
+	})
 
 	jb := openDB()
 	defer jb.closeDB()
 
-	results := BatchScript(requests, 30)
-	for _, res := range results {
-		results, _ := ioutil.ReadAll(res.Res.Body)
-		removedEndingEcho := strings.Split(string(results), "Result: ")
+	for i, output := range outputs {
+		removedEndingEcho := strings.Split(output, "Result: ")
 		cleanedUpList := strings.Split(removedEndingEcho[0], "\n")
 		//teamURL := strings.Replace(res.URL, "/scriptText", "", -1)
 		//team := strings.Replace(teamURL, "/teams-", "", -1)
@@ -221,7 +305,8 @@ func BuildJobIndex(Jenky *gojenkins.Jenkins) {
 				job := jobURLSplit[0]
 				gitURL := strings.Replace(jobURLSplit[1], "https://", "", -1)
 				branchIndex := strings.LastIndex(job, "/")
-				if branchIndex > 0 {
+				// A controller may hold jobs the topology sends elsewhere
+				if branchIndex > 0 && client.Team(job) == teams[i] {
 					jobURLMinusBranch := job[0:branchIndex]
 					//fullJobURL := fmt.Sprintf("%s", team, jobURLMinusBranch)
 					//fmt.Println(gitURL)
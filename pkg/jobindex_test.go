@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildJobIndex(t *testing.T) {
+	_, client, stop := startFakeJenkins(t)
+	defer stop()
+
+	BuildJobIndex(client)
+	want := []string{"example/app"}
+	if got := GetAffiliatedJobs("github.com/example/app.git"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAffiliatedJobs() = %v, want %v", got, want)
+	}
+	if got := GetAffiliatedJobs("github.com/example/unknown.git"); len(got) != 0 {
+		t.Errorf("GetAffiliatedJobs() of an unknown repo = %v", got)
+	}
+
+	// Indexing again doesn't add the job twice
+	BuildJobIndex(client)
+	if got := GetAffiliatedJobs("github.com/example/app.git"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAffiliatedJobs() after reindexing = %v, want %v", got, want)
+	}
+}
+
+func TestUseStore(t *testing.T) {
+	oldIndex, oldHistory, oldName := jobIndexPath, historyPath, storeName
+	defer func() { jobIndexPath, historyPath, storeName = oldIndex, oldHistory, oldName }()
+
+	tests := []struct {
+		key         string
+		wantIndex   string
+		wantHistory string
+	}{
+		{key: "context-prod", wantIndex: "jenkins-db-context-prod", wantHistory: "jenkins-history-context-prod"},
+		{key: "https://ci.example.com/", wantIndex: "jenkins-db-https_ci.example.com", wantHistory: "jenkins-history-https_ci.example.com"},
+		{key: "", wantIndex: "jenkins-db", wantHistory: "jenkins-history"},
+	}
+	for _, tt := range tests {
+		UseStore(tt.key)
+		if filepath.Base(jobIndexPath) != tt.wantIndex || filepath.Base(historyPath) != tt.wantHistory {
+			t.Errorf("UseStore(%q) = %s, %s, want %s, %s", tt.key, jobIndexPath, historyPath, tt.wantIndex, tt.wantHistory)
+		}
+	}
+}
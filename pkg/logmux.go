@@ -0,0 +1,171 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	color "github.com/logrusorgru/aurora/v3"
+)
+
+var tagColors = []func(interface{}) color.Value{
+	color.Cyan,
+	color.Magenta,
+	color.Green,
+	color.Yellow,
+	color.Blue,
+	color.BrightCyan,
+	color.BrightMagenta,
+	color.BrightGreen,
+	color.BrightYellow,
+	color.BrightBlue,
+}
+
+// LogMux lets several builds share one writer. Every line is written whole,
+// prefixed with a colored tag naming the build it came from.
+type LogMux struct {
+	mu    sync.Mutex
+	out   io.Writer
+	width int
+	count int
+}
+
+// NewLogMux creates a LogMux writing to out. Tags are padded to width so the
+// log lines line up.
+func NewLogMux(out io.Writer, width int) *LogMux {
+	return &LogMux{out: out, width: width}
+}
+
+// Writer returns a writer for one build. Partial lines are held back until
+// their newline arrives or the writer is closed.
+func (m *LogMux) Writer(tag string) io.WriteCloser {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paint := tagColors[m.count%len(tagColors)]
+	m.count++
+	prefix := fmt.Sprintf("%s ", paint(fmt.Sprintf("%-*s |", m.width, tag)))
+	return &prefixWriter{mux: m, prefix: prefix}
+}
+
+func (m *LogMux) writeLine(prefix string, line []byte) {
+	// Written with a single call so lines from separate muxes sharing stdout
+	// don't get mixed up either.
+	whole := make([]byte, 0, len(prefix)+len(line)+1)
+	whole = append(whole, prefix...)
+	whole = append(whole, line...)
+	whole = append(whole, '\n')
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.out.Write(whole)
+}
+
+type prefixWriter struct {
+	mux    *LogMux
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buf.Next(i + 1)
+		w.mux.writeLine(w.prefix, bytes.TrimRight(line, "\r\n"))
+	}
+	return len(p), nil
+}
+
+func (w *prefixWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.mux.writeLine(w.prefix, w.buf.Bytes())
+		w.buf.Reset()
+	}
+	return nil
+}
+
+// BuildTag names a single run of a batch job by its job and the values it
+// was given for the substitutes, e.g. "Folder/main tenant=a".
+func BuildTag(jobBatch BatchJob, params map[string]string) string {
+	segments := strings.Split(strings.Trim(jobBatch.Job, "/"), "/")
+	if len(segments) > 2 {
+		segments = segments[len(segments)-2:]
+	}
+	tag := strings.Join(segments, "/")
+
+	keys := make([]string, 0, len(jobBatch.Substitute))
+	for key := range jobBatch.Substitute {
+		keys = append(keys, key)
+	}
+	for _, include := range jobBatch.Include {
+		for key := range include {
+			if _, ok := jobBatch.Substitute[key]; !ok {
+				if _, ok := jobBatch.Variables[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if value, ok := params[key]; ok && !seen[key] {
+			tag += fmt.Sprintf(" %s=%s", key, value)
+			seen[key] = true
+		}
+	}
+	return tag
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._=-]+`)
+
+// CreateLogFile creates the file in dir that a build's full log is written to.
+// Files are never reused: when a run with the same tag already has one, e.g.
+// the same job in two stages, a number is added to the name.
+func CreateLogFile(dir string, tag string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(tag, "_"), "_")
+	filename := filepath.Join(dir, name+".log")
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return file, err
+		}
+		filename = filepath.Join(dir, fmt.Sprintf("%s-%d.log", name, n))
+	}
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogMuxWholeLines(t *testing.T) {
+	var out bytes.Buffer
+	mux := NewLogMux(&out, 3)
+	a := mux.Writer("a")
+	b := mux.Writer("bb")
+
+	a.Write([]byte("first half "))
+	b.Write([]byte("from b\n"))
+	a.Write([]byte("second half\nand a tail"))
+	a.Close()
+	b.Close()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []struct{ tag, text string }{
+		{"bb  |", "from b"},
+		{"a   |", "first half second half"},
+		{"a   |", "and a tail"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("LogMux wrote %d lines, want %d: %q", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, want[i].tag) || !strings.HasSuffix(line, want[i].text) {
+			t.Errorf("line %d = %q, want tag %q and text %q", i, line, want[i].tag, want[i].text)
+		}
+	}
+}
+
+func TestBuildTag(t *testing.T) {
+	jobBatch := BatchJob{
+		Job:        "Example/Folder/mainbranch",
+		Variables:  map[string]string{"dry_run": "false"},
+		Substitute: map[string][]string{"tenant": {"a"}, "region": {"x"}},
+	}
+	params := map[string]string{"tenant": "a", "region": "x", "dry_run": "false"}
+	if got, want := BuildTag(jobBatch, params), "Folder/mainbranch region=x tenant=a"; got != want {
+		t.Errorf("BuildTag() = %q, want %q", got, want)
+	}
+}
+
+func TestCreateLogFileUnique(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goose-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := []string{"Folder_mainbranch_tenant=a.log", "Folder_mainbranch_tenant=a-2.log", "Folder_mainbranch_tenant=a-3.log"}
+	for i, name := range want {
+		file, err := CreateLogFile(dir, "Folder/mainbranch tenant=a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(name)
+		file.Close()
+		if file.Name() != filepath.Join(dir, name) {
+			t.Errorf("CreateLogFile() #%d = %s, want %s", i, file.Name(), name)
+		}
+	}
+	for _, name := range want {
+		if content, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil || string(content) != name {
+			t.Errorf("%s = %q, %v", name, content, err)
+		}
+	}
+}
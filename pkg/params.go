@@ -0,0 +1,106 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// The kinds of parameter definitions jenkins reports in a job's Type.
+const (
+	BooleanParameter  = "BooleanParameterDefinition"
+	ChoiceParameter   = "ChoiceParameterDefinition"
+	StringParameter   = "StringParameterDefinition"
+	TextParameter     = "TextParameterDefinition"
+	PasswordParameter = "PasswordParameterDefinition"
+	FileParameter     = "FileParameterDefinition"
+	RunParameter      = "RunParameterDefinition"
+)
+
+// A run parameter points at a build of another job, e.g. Folder/job#12
+var runParameterValue = regexp.MustCompile(`^.+#[0-9]+$`)
+
+// ParameterDefault returns the default value of a parameter as jenkins
+// expects it back.
+func ParameterDefault(param gojenkins.ParameterDefinition) string {
+	if param.DefaultParameterValue.Value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", param.DefaultParameterValue.Value)
+}
+
+// CheckParameter validates value against the declared type of param and
+// returns it the way jenkins expects it, e.g. "True" becomes "true".
+func CheckParameter(param gojenkins.ParameterDefinition, value string) (string, error) {
+	switch param.Type {
+	case BooleanParameter:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", errors.Errorf("%s must be true or false, not %q", param.Name, value)
+		}
+		return strconv.FormatBool(b), nil
+	case ChoiceParameter:
+		for _, choice := range param.Choices {
+			if choice == value {
+				return value, nil
+			}
+		}
+		return "", errors.Errorf("%s must be one of %v, not %q", param.Name, param.Choices, value)
+	case FileParameter:
+		if value == "" {
+			return value, nil
+		}
+		info, err := os.Stat(value)
+		if err != nil {
+			return "", errors.Wrapf(err, "%s must be a file", param.Name)
+		}
+		if info.IsDir() {
+			return "", errors.Errorf("%s must be a file, %s is a directory", param.Name, value)
+		}
+		return value, nil
+	case RunParameter:
+		if value != "" && !runParameterValue.MatchString(value) {
+			return "", errors.Errorf("%s must point at a build like job#number, not %q", param.Name, value)
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// FindParameter looks a parameter definition up by name.
+func FindParameter(params []gojenkins.ParameterDefinition, name string) (gojenkins.ParameterDefinition, bool) {
+	for _, param := range params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return gojenkins.ParameterDefinition{}, false
+}
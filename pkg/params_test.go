@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestCheckParameter(t *testing.T) {
+	file, err := ioutil.TempFile("", "goose-param")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	boolean := gojenkins.ParameterDefinition{Name: "dry_run", Type: BooleanParameter}
+	choice := gojenkins.ParameterDefinition{Name: "tenant", Type: ChoiceParameter, Choices: []string{"a", "b"}}
+	upload := gojenkins.ParameterDefinition{Name: "manifest", Type: FileParameter}
+	run := gojenkins.ParameterDefinition{Name: "upstream", Type: RunParameter}
+	text := gojenkins.ParameterDefinition{Name: "notes", Type: TextParameter}
+
+	tests := []struct {
+		name    string
+		param   gojenkins.ParameterDefinition
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "TestBoolean", param: boolean, value: "True", want: "true"},
+		{name: "TestBadBoolean", param: boolean, value: "yes please", wantErr: true},
+		{name: "TestChoice", param: choice, value: "b", want: "b"},
+		{name: "TestBadChoice", param: choice, value: "c", wantErr: true},
+		{name: "TestFile", param: upload, value: file.Name(), want: file.Name()},
+		{name: "TestMissingFile", param: upload, value: file.Name() + ".missing", wantErr: true},
+		{name: "TestDirectory", param: upload, value: os.TempDir(), wantErr: true},
+		{name: "TestRun", param: run, value: "Example/Folder/main#12", want: "Example/Folder/main#12"},
+		{name: "TestBadRun", param: run, value: "Example/Folder/main", wantErr: true},
+		{name: "TestText", param: text, value: "line one\nline two", want: "line one\nline two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckParameter(tt.param, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckParameter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CheckParameter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
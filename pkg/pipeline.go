@@ -0,0 +1,260 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// Statuses of pipeline stages as reported by the workflow REST API.
+const (
+	PipelineSuccess     = "SUCCESS"
+	PipelineFailed      = "FAILED"
+	PipelineInProgress  = "IN_PROGRESS"
+	PipelineNotExecuted = "NOT_EXECUTED"
+	PipelineAborted     = "ABORTED"
+	PipelineUnstable    = "UNSTABLE"
+	PipelinePaused      = "PAUSED_PENDING_INPUT"
+)
+
+// Parallel branches show up among a stage's flow nodes with this prefix.
+const branchPrefix = "Branch: "
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// consoleBlock is the log on the page of a flow node.
+var consoleBlock = regexp.MustCompile(`(?s)<pre[^>]*>(.*)</pre>`)
+
+// PipelineStage is a stage of a pipeline run, or a parallel branch of one.
+type PipelineStage struct {
+	ID             string          `json:"id" yaml:"id"`
+	Name           string          `json:"name" yaml:"name"`
+	Status         string          `json:"status" yaml:"status"`
+	Started        time.Time       `json:"started" yaml:"started"`
+	DurationMillis int64           `json:"durationMillis" yaml:"durationMillis"`
+	Branches       []PipelineStage `json:"branches,omitempty" yaml:"branches,omitempty"`
+}
+
+// Duration is how long the stage ran, or has been running.
+func (s PipelineStage) Duration() time.Duration {
+	return time.Duration(s.DurationMillis) * time.Millisecond
+}
+
+// Pipeline is the stage view of a pipeline run.
+type Pipeline struct {
+	Status         string          `json:"status" yaml:"status"`
+	DurationMillis int64           `json:"durationMillis" yaml:"durationMillis"`
+	Stages         []PipelineStage `json:"stages" yaml:"stages"`
+}
+
+// wfapiNode is a run, stage or flow node as returned by wfapi/describe.
+type wfapiNode struct {
+	ID             string      `json:"id"`
+	Name           string      `json:"name"`
+	Status         string      `json:"status"`
+	StartTime      int64       `json:"startTimeMillis"`
+	Duration       int64       `json:"durationMillis"`
+	ParentNodes    []string    `json:"parentNodes"`
+	Stages         []wfapiNode `json:"stages"`
+	StageFlowNodes []wfapiNode `json:"stageFlowNodes"`
+}
+
+// wfapiLog is the tail of a flow node's log. HasMore is set when the text
+// was cut short.
+type wfapiLog struct {
+	Text    string `json:"text"`
+	HasMore bool   `json:"hasMore"`
+}
+
+func (n wfapiNode) stage() PipelineStage {
+	return PipelineStage{
+		ID:             n.ID,
+		Name:           strings.TrimPrefix(n.Name, branchPrefix),
+		Status:         n.Status,
+		Started:        time.Unix(0, n.StartTime*int64(time.Millisecond)),
+		DurationMillis: n.Duration,
+	}
+}
+
+// getWfapi reads a workflow REST API endpoint of a build.
+func getWfapi(ctx context.Context, B *gojenkins.Build, endpoint string, v interface{}) error {
+	resp, err := B.Jenkins.Requester.Get(ctx, B.Base+endpoint, v, nil)
+	if err != nil {
+		return errors.Wrapf(err, "could not get %s", endpoint)
+	}
+	if resp.StatusCode != 200 {
+		return errors.Errorf("could not get %s of %s: %s, is it a pipeline?", endpoint, B.GetUrl(), resp.Status)
+	}
+	return nil
+}
+
+// GetPipeline describes the stages of a pipeline build, including the
+// parallel branches inside each stage.
+func GetPipeline(ctx context.Context, B *gojenkins.Build) (*Pipeline, error) {
+	run := wfapiNode{}
+	if err := getWfapi(ctx, B, "/wfapi/describe", &run); err != nil {
+		return nil, err
+	}
+	pipeline := &Pipeline{
+		Status:         run.Status,
+		DurationMillis: run.Duration,
+	}
+	for _, node := range run.Stages {
+		stage := node.stage()
+		described := wfapiNode{}
+		if err := getWfapi(ctx, B, "/execution/node/"+node.ID+"/wfapi/describe", &described); err != nil {
+			return nil, err
+		}
+		for _, flowNode := range described.StageFlowNodes {
+			if strings.HasPrefix(flowNode.Name, branchPrefix) {
+				stage.Branches = append(stage.Branches, flowNode.stage())
+			}
+		}
+		pipeline.Stages = append(pipeline.Stages, stage)
+	}
+	return pipeline, nil
+}
+
+// FindStage looks a stage or parallel branch up by name or id.
+func (p *Pipeline) FindStage(name string) (PipelineStage, bool) {
+	for _, stage := range p.Stages {
+		if stage.Name == name || stage.ID == name {
+			return stage, true
+		}
+		for _, branch := range stage.Branches {
+			if branch.Name == name || branch.ID == name {
+				return branch, true
+			}
+		}
+	}
+	return PipelineStage{}, false
+}
+
+// GetStageLog returns the console output of the steps of one stage or
+// parallel branch.
+func GetStageLog(ctx context.Context, B *gojenkins.Build, p *Pipeline, name string) (string, error) {
+	stage, ok := p.FindStage(name)
+	if !ok {
+		return "", errors.Errorf("the pipeline has no stage %s", name)
+	}
+	// Branches are described as part of the stage holding them
+	parent := stage.ID
+	for _, s := range p.Stages {
+		for _, branch := range s.Branches {
+			if branch.ID == stage.ID {
+				parent = s.ID
+			}
+		}
+	}
+	described := wfapiNode{}
+	if err := getWfapi(ctx, B, "/execution/node/"+parent+"/wfapi/describe", &described); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, node := range stepsOf(described.StageFlowNodes, stage.ID, parent != stage.ID) {
+		log, err := nodeLog(ctx, B, node.ID)
+		if err != nil {
+			return "", err
+		}
+		output.WriteString(log)
+	}
+	return output.String(), nil
+}
+
+// nodeLog returns the log of one flow node. The workflow API only serves
+// part of long logs, those are read from the node's log page instead. When
+// that fails the log ends with a note that it was cut short.
+func nodeLog(ctx context.Context, B *gojenkins.Build, id string) (string, error) {
+	log := wfapiLog{}
+	if err := getWfapi(ctx, B, "/execution/node/"+id+"/wfapi/log", &log); err != nil {
+		return "", err
+	}
+	if !log.HasMore {
+		return cleanLog(log.Text), nil
+	}
+	page := ""
+	endpoint := B.Base + "/execution/node/" + id + "/log"
+	resp, err := B.Jenkins.Requester.Get(ctx, endpoint, &page, nil)
+	if err == nil && resp.StatusCode == 200 {
+		if match := consoleBlock.FindStringSubmatch(page); match != nil {
+			return cleanLog(match[1]), nil
+		}
+	}
+	text := cleanLog(log.Text)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text + fmt.Sprintf("[log truncated, the full log is at %s%s]\n", B.Jenkins.Server, endpoint), nil
+}
+
+// stepsOf returns the flow nodes of a stage, or only the ones descending
+// from the branch node when branch is set.
+func stepsOf(nodes []wfapiNode, id string, branch bool) []wfapiNode {
+	if !branch {
+		return nodes
+	}
+	parents := map[string][]string{}
+	for _, node := range nodes {
+		parents[node.ID] = node.ParentNodes
+	}
+	var descends func(node string, seen map[string]bool) bool
+	descends = func(node string, seen map[string]bool) bool {
+		if node == id {
+			return true
+		}
+		if seen[node] {
+			return false
+		}
+		seen[node] = true
+		for _, parent := range parents[node] {
+			if descends(parent, seen) {
+				return true
+			}
+		}
+		return false
+	}
+
+	steps := []wfapiNode{}
+	for _, node := range nodes {
+		if node.ID != id && descends(node.ID, map[string]bool{}) {
+			steps = append(steps, node)
+		}
+	}
+	return steps
+}
+
+// cleanLog turns the html the workflow API serves logs as into plain text.
+func cleanLog(text string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+const describeRun = `{"status": "FAILED", "durationMillis": 90000, "stages": [
+	{"id": "6", "name": "Build", "status": "SUCCESS", "durationMillis": 12000},
+	{"id": "12", "name": "Test", "status": "FAILED", "durationMillis": 63000}
+]}`
+
+const describeTest = `{"id": "12", "name": "Test", "stageFlowNodes": [
+	{"id": "15", "name": "Branch: linux", "status": "SUCCESS", "durationMillis": 50000, "parentNodes": ["12"]},
+	{"id": "16", "name": "Branch: windows", "status": "FAILED", "durationMillis": 63000, "parentNodes": ["12"]},
+	{"id": "18", "name": "Shell Script", "status": "SUCCESS", "parentNodes": ["15"]},
+	{"id": "19", "name": "Shell Script", "status": "FAILED", "parentNodes": ["16"]},
+	{"id": "21", "name": "Print Message", "status": "FAILED", "parentNodes": ["19"]}
+]}`
+
+func TestGetPipeline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case "/job/Example/1/wfapi/describe":
+			fmt.Fprint(w, describeRun)
+		case "/job/Example/1/execution/node/6/wfapi/describe":
+			fmt.Fprint(w, `{"id": "6", "stageFlowNodes": [{"id": "8", "name": "Shell Script", "parentNodes": ["6"]}]}`)
+		case "/job/Example/1/execution/node/12/wfapi/describe":
+			fmt.Fprint(w, describeTest)
+		case "/job/Example/1/execution/node/19/wfapi/log":
+			fmt.Fprint(w, `{"text": "<span class=\"pipeline-node-19\">make test\n&gt; 1 failed\n</span>"}`)
+		case "/job/Example/1/execution/node/21/wfapi/log":
+			fmt.Fprint(w, `{"text": "windows is broken\n"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	jenkins := gojenkins.CreateJenkins(server.Client(), server.URL)
+	build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/Example/1", Raw: &gojenkins.BuildResponse{}}
+
+	pipeline, err := GetPipeline(context.Background(), build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipeline.Stages) != 2 || pipeline.Status != PipelineFailed {
+		t.Fatalf("GetPipeline() = %+v", pipeline)
+	}
+	branches := pipeline.Stages[1].Branches
+	if len(pipeline.Stages[0].Branches) != 0 || len(branches) != 2 || branches[1].Name != "windows" || branches[1].Status != PipelineFailed {
+		t.Errorf("GetPipeline() branches = %+v", branches)
+	}
+
+	output, err := GetStageLog(context.Background(), build, pipeline, "windows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "make test\n> 1 failed\nwindows is broken\n"; output != want {
+		t.Errorf("GetStageLog() = %q, want %q", output, want)
+	}
+
+	if _, err := GetStageLog(context.Background(), build, pipeline, "Deploy"); err == nil {
+		t.Error("GetStageLog() of a missing stage should fail")
+	}
+}
+
+func TestGetStageLogTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{name: "TestFullLog", page: `<html><body><pre class="console-output">line 1
+<span>line 2</span>
+line 3 &amp; more
+</pre></body></html>`, want: "line 1\nline 2\nline 3 & more\n"},
+		{name: "TestNoLogPage", want: "line 1\n[log truncated, the full log is at SERVER/job/Example/1/execution/node/8/log]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch strings.TrimSuffix(r.URL.Path, "/") {
+				case "/job/Example/1/wfapi/describe":
+					fmt.Fprint(w, `{"status": "FAILED", "stages": [{"id": "6", "name": "Build", "status": "FAILED"}]}`)
+				case "/job/Example/1/execution/node/6/wfapi/describe":
+					fmt.Fprint(w, `{"id": "6", "stageFlowNodes": [{"id": "8", "name": "Shell Script", "parentNodes": ["6"]}]}`)
+				case "/job/Example/1/execution/node/8/wfapi/log":
+					fmt.Fprint(w, `{"text": "line 1", "hasMore": true, "consoleUrl": "/job/Example/1/execution/node/8/log"}`)
+				case "/job/Example/1/execution/node/8/log":
+					if tt.page == "" {
+						http.NotFound(w, r)
+						return
+					}
+					fmt.Fprint(w, tt.page)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			jenkins := gojenkins.CreateJenkins(server.Client(), server.URL)
+			build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/Example/1", Raw: &gojenkins.BuildResponse{}}
+			pipeline, err := GetPipeline(context.Background(), build)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, err := GetStageLog(context.Background(), build, pipeline, "Build")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.Replace(tt.want, "SERVER", server.URL, 1); output != want {
+				t.Errorf("GetStageLog() = %q, want %q", output, want)
+			}
+		})
+	}
+}
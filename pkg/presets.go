@@ -0,0 +1,69 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+// Preset is a named set of parameter values for a job, saved in the goose
+// config so a build can be started again without answering every prompt.
+type Preset struct {
+	Job        string            `mapstructure:"job" json:"job" yaml:"job"`
+	Name       string            `mapstructure:"name" json:"name" yaml:"name"`
+	Parameters map[string]string `mapstructure:"parameters" json:"parameters" yaml:"parameters"`
+}
+
+// FindPresets returns the presets matching job and name. An empty job or
+// name matches every preset.
+func FindPresets(presets []Preset, job string, name string) []Preset {
+	found := []Preset{}
+	for _, preset := range presets {
+		if (job == "" || preset.Job == job) && (name == "" || preset.Name == name) {
+			found = append(found, preset)
+		}
+	}
+	return found
+}
+
+// SavePreset adds p to presets, replacing a preset with the same job and name.
+func SavePreset(presets []Preset, p Preset) []Preset {
+	for i, preset := range presets {
+		if preset.Job == p.Job && preset.Name == p.Name {
+			presets[i] = p
+			return presets
+		}
+	}
+	return append(presets, p)
+}
+
+// DeletePresets removes the presets matching job and name, see FindPresets.
+// It returns the remaining presets and how many were removed.
+func DeletePresets(presets []Preset, job string, name string) ([]Preset, int) {
+	kept := []Preset{}
+	for _, preset := range presets {
+		if (job == "" || preset.Job == job) && (name == "" || preset.Name == name) {
+			continue
+		}
+		kept = append(kept, preset)
+	}
+	return kept, len(presets) - len(kept)
+}
@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	presets := []Preset{
+		{Job: "Example/Folder/main", Name: "canary", Parameters: map[string]string{"tenant": "a"}},
+		{Job: "Example/Folder/main", Name: "all", Parameters: map[string]string{"tenant": "*"}},
+		{Job: "Example/Other/main", Name: "canary", Parameters: map[string]string{"region": "x"}},
+	}
+
+	tests := []struct {
+		name string
+		job  string
+		pick string
+		want int
+	}{
+		{name: "TestAll", want: 3},
+		{name: "TestJob", job: "Example/Folder/main", want: 2},
+		{name: "TestName", pick: "canary", want: 2},
+		{name: "TestBoth", job: "Example/Other/main", pick: "canary", want: 1},
+		{name: "TestMissing", job: "Example/Other/main", pick: "all", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindPresets(presets, tt.job, tt.pick); len(got) != tt.want {
+				t.Errorf("FindPresets() = %v, want %d presets", got, tt.want)
+			}
+		})
+	}
+
+	updated := Preset{Job: "Example/Folder/main", Name: "canary", Parameters: map[string]string{"tenant": "b"}}
+	presets = SavePreset(presets, updated)
+	if len(presets) != 3 || !reflect.DeepEqual(presets[0], updated) {
+		t.Errorf("SavePreset() did not replace the existing preset: %v", presets)
+	}
+	presets = SavePreset(presets, Preset{Job: "Example/Folder/main", Name: "new"})
+	if len(presets) != 4 {
+		t.Errorf("SavePreset() did not add a new preset: %v", presets)
+	}
+
+	presets, removed := DeletePresets(presets, "", "canary")
+	if removed != 2 || len(presets) != 2 {
+		t.Errorf("DeletePresets() removed %d, left %v", removed, presets)
+	}
+}
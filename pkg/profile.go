@@ -0,0 +1,129 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Profile is a named jenkins goose can talk to, with the user to log in as
+// and where to get their api key. Profiles are kept under contexts in the
+// goose config and picked with goose context use or --context.
+type Profile struct {
+	Name     string `mapstructure:"name" json:"name" yaml:"name"`
+	URL      string `mapstructure:"url" json:"url" yaml:"url"`
+	LoginURL string `mapstructure:"login-url" json:"loginUrl,omitempty" yaml:"login-url,omitempty"`
+	User     string `mapstructure:"user" json:"user" yaml:"user"`
+	// The api key is read from the environment variable APIKeyEnv, printed
+	// by the shell command APIKeyCommand, or given as APIKey, in that order.
+	APIKeyEnv     string   `mapstructure:"api-key-env" json:"apiKeyEnv,omitempty" yaml:"api-key-env,omitempty"`
+	APIKeyCommand string   `mapstructure:"api-key-command" json:"apiKeyCommand,omitempty" yaml:"api-key-command,omitempty"`
+	APIKey        string   `mapstructure:"api-key" json:"-" yaml:"api-key,omitempty"`
+	Topology      Topology `mapstructure:"topology" json:"topology" yaml:"topology,omitempty"`
+}
+
+// JenkinsTopology is the profile's topology, with its urls filled in from
+// URL and LoginURL.
+func (p Profile) JenkinsTopology() Topology {
+	topology := p.Topology
+	if topology.Root == "" {
+		topology.Root = p.URL
+	}
+	if topology.Login == "" {
+		topology.Login = p.LoginURL
+	}
+	return topology
+}
+
+// CredentialSource describes where the api key comes from without showing
+// it.
+func (p Profile) CredentialSource() string {
+	switch {
+	case p.APIKeyEnv != "":
+		return "$" + p.APIKeyEnv
+	case p.APIKeyCommand != "":
+		return "command: " + p.APIKeyCommand
+	case p.APIKey != "":
+		return "config"
+	}
+	return "none"
+}
+
+// GetAPIKey reads the profile's api key from its source.
+func (p Profile) GetAPIKey() (string, error) {
+	switch {
+	case p.APIKeyEnv != "":
+		key := os.Getenv(p.APIKeyEnv)
+		if key == "" {
+			return "", errors.Errorf("context %s reads its api key from $%s, which is empty", p.Name, p.APIKeyEnv)
+		}
+		return key, nil
+	case p.APIKeyCommand != "":
+		out, err := exec.Command("sh", "-c", p.APIKeyCommand).Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "could not get the api key of context %s from %q", p.Name, p.APIKeyCommand)
+		}
+		return strings.TrimSpace(string(out)), nil
+	case p.APIKey != "":
+		return p.APIKey, nil
+	}
+	return "", errors.Errorf("context %s has no api key, set api-key-env, api-key-command or api-key", p.Name)
+}
+
+// Validate checks that the profile can be connected to.
+func (p Profile) Validate() error {
+	if p.Name == "" {
+		return errors.New("a context needs a name")
+	}
+	if p.User == "" {
+		return errors.Errorf("context %s has no user", p.Name)
+	}
+	return errors.Wrapf(p.JenkinsTopology().Validate(), "context %s", p.Name)
+}
+
+// FindProfile looks a profile up by name.
+func FindProfile(profiles []Profile, name string) (Profile, bool) {
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// SaveProfile adds p to profiles, replacing the profile of the same name.
+func SaveProfile(profiles []Profile, p Profile) []Profile {
+	for i, profile := range profiles {
+		if profile.Name == p.Name {
+			profiles[i] = p
+			return profiles
+		}
+	}
+	return append(profiles, p)
+}
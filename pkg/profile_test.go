@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestProfileGetAPIKey(t *testing.T) {
+	os.Setenv("GOOSE_TEST_API_KEY", "from-env")
+	defer os.Unsetenv("GOOSE_TEST_API_KEY")
+
+	tests := []struct {
+		name    string
+		profile Profile
+		want    string
+		wantErr bool
+	}{
+		{name: "TestEnv", profile: Profile{APIKeyEnv: "GOOSE_TEST_API_KEY", APIKey: "ignored"}, want: "from-env"},
+		{name: "TestCommand", profile: Profile{APIKeyCommand: "echo from-command"}, want: "from-command"},
+		{name: "TestConfig", profile: Profile{APIKey: "from-config"}, want: "from-config"},
+		{name: "TestEmptyEnv", profile: Profile{APIKeyEnv: "GOOSE_TEST_MISSING_KEY"}, wantErr: true},
+		{name: "TestFailingCommand", profile: Profile{APIKeyCommand: "exit 3"}, wantErr: true},
+		{name: "TestNoKey", profile: Profile{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.profile.GetAPIKey()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetAPIKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		wantErr bool
+	}{
+		{name: "TestTeams", profile: Profile{Name: "prod", URL: "https://ci.example.com", LoginURL: "https://ci.example.com/cjoc", User: "me"}},
+		{name: "TestFlat", profile: Profile{Name: "sandbox", URL: "https://sandbox.example.com", User: "me", Topology: Topology{Kind: TopologyFlat}}},
+		{name: "TestNoName", profile: Profile{URL: "https://sandbox.example.com", User: "me", Topology: Topology{Kind: TopologyFlat}}, wantErr: true},
+		{name: "TestNoUser", profile: Profile{Name: "sandbox", URL: "https://sandbox.example.com", Topology: Topology{Kind: TopologyFlat}}, wantErr: true},
+		{name: "TestTeamsWithoutLogin", profile: Profile{Name: "prod", URL: "https://ci.example.com", User: "me"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.profile.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveProfile(t *testing.T) {
+	profiles := SaveProfile(nil, Profile{Name: "prod", URL: "https://ci.example.com"})
+	profiles = SaveProfile(profiles, Profile{Name: "sandbox", URL: "https://sandbox.example.com"})
+	profiles = SaveProfile(profiles, Profile{Name: "prod", URL: "https://ci2.example.com"})
+
+	want := []Profile{{Name: "prod", URL: "https://ci2.example.com"}, {Name: "sandbox", URL: "https://sandbox.example.com"}}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("SaveProfile() = %+v, want %+v", profiles, want)
+	}
+	if got, ok := FindProfile(profiles, "sandbox"); !ok || got.URL != "https://sandbox.example.com" {
+		t.Errorf("FindProfile() = %+v, %v", got, ok)
+	}
+	if _, ok := FindProfile(profiles, "nope"); ok {
+		t.Error("FindProfile() found a missing profile")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// QueueItem is a build waiting in a team's queue.
+type QueueItem struct {
+	Team       string            `json:"team" yaml:"team"`
+	ID         int64             `json:"id" yaml:"id"`
+	Job        string            `json:"job" yaml:"job"`
+	URL        string            `json:"url" yaml:"url"`
+	Why        string            `json:"why" yaml:"why"`
+	Since      time.Time         `json:"since" yaml:"since"`
+	Blocked    bool              `json:"blocked" yaml:"blocked"`
+	Stuck      bool              `json:"stuck" yaml:"stuck"`
+	Parameters map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// Waiting is how long the item has been queued.
+func (i QueueItem) Waiting() time.Duration {
+	return time.Since(i.Since)
+}
+
+// GetTeams lists the teams of the topology, in the teams layout the ones
+// visible from its login url.
+func GetTeams(J *gojenkins.Jenkins, topology Topology) ([]string, error) {
+	if topology.kind() != TopologyTeams {
+		return topology.FixedTeams(), nil
+	}
+	jobs, err := topFolders(J, topology)
+	if err != nil {
+		return nil, err
+	}
+	teams := []string{}
+	for _, job := range jobs {
+		teams = append(teams, job.Name)
+	}
+	return teams, nil
+}
+
+// topFolders lists what is at the top of the topology: the team
+// controllers at the login url in the teams layout, the jobs at the root
+// url in the flat layout, and the folder prefixes followed by the other jobs
+// at the root url in the folders layout.
+func topFolders(J *gojenkins.Jenkins, topology Topology) ([]gojenkins.InnerJob, error) {
+	switch topology.kind() {
+	case TopologyFolders:
+		jobs := []gojenkins.InnerJob{}
+		for _, team := range topology.FixedTeams() {
+			if team != "" {
+				jobs = append(jobs, gojenkins.InnerJob{Name: team, Url: topology.ControllerURL(team)})
+			}
+		}
+		root, err := viewAt(J, topology.Root).GetAllJobNames(context.TODO())
+		if err != nil {
+			return nil, errors.Wrap(err, "could not list jobs")
+		}
+		for _, job := range root {
+			// Folders mapped to a controller are listed as their prefix
+			if topology.Team(job.Name) == "" {
+				jobs = append(jobs, job)
+			}
+		}
+		return jobs, nil
+	case TopologyFlat:
+		jobs, err := viewAt(J, topology.Root).GetAllJobNames(context.TODO())
+		return jobs, errors.Wrap(err, "could not list jobs")
+	}
+	jobs, err := viewAt(J, topology.Login).GetAllJobNames(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list teams")
+	}
+	return jobs, nil
+}
+
+// GetQueueItems lists the queued builds of every team.
+func GetQueueItems(J *gojenkins.Jenkins, topology Topology, teams []string) ([]QueueItem, error) {
+	items := []QueueItem{}
+	for _, team := range teams {
+		queue, err := topology.View(J, team).GetQueue(context.TODO())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get the queue of %s", team)
+		}
+		for _, raw := range queue.Raw.Items {
+			params := map[string]string{}
+			for _, action := range raw.Actions {
+				for _, param := range action.Parameters {
+					params[param.Name] = param.Value
+				}
+			}
+			items = append(items, QueueItem{
+				Team:       team,
+				ID:         raw.ID,
+				Job:        jobPathFromURL(topology, raw.Task.URL),
+				URL:        raw.Task.URL,
+				Why:        raw.Why,
+				Since:      time.Unix(0, raw.InQueueSince*int64(time.Millisecond)),
+				Blocked:    raw.Blocked,
+				Stuck:      raw.Stuck,
+				Parameters: params,
+			})
+		}
+	}
+	return items, nil
+}
+
+// CancelQueueItem takes the item with the given id off the queue of team.
+func CancelQueueItem(J *gojenkins.Jenkins, topology Topology, team string, id int64) error {
+	queue, err := topology.View(J, team).GetQueue(context.TODO())
+	if err != nil {
+		return errors.Wrapf(err, "could not get the queue of %s", team)
+	}
+	task := queue.GetTaskById(id)
+	if task == nil {
+		return errors.Errorf("%s has no queue item %d", team, id)
+	}
+	if _, err := task.Cancel(context.TODO()); err != nil {
+		return errors.Wrapf(err, "could not cancel queue item %d", id)
+	}
+	return nil
+}
+
+// jobPathFromURL turns a job URL such as
+// https://ci/teams-team/job/Folder/job/main/ into Folder/main
+func jobPathFromURL(topology Topology, jobURL string) string {
+	path, _ := topology.JobPath(jobURL)
+	return path
+}
@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestJobPathFromURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "TestTeam", url: "https://ci.example.com/teams-example/job/Example/job/Folder/job/main/", want: "Example/Folder/main"},
+		{name: "TestEscapedBranch", url: "https://ci.example.com/teams-example/job/Example/job/feature%252Fx/", want: "Example/feature%252Fx"},
+		{name: "TestNoTeam", url: "https://ci.example.com/job/Example/", want: "Example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jobPathFromURL(Topology{Root: "https://ci.example.com"}, tt.url); got != tt.want {
+				t.Errorf("jobPathFromURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetQueueItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/teams-example/queue/api/json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"items": [
+			{"id": 7, "why": "Waiting for next available executor", "inQueueSince": 1614600000000,
+			 "task": {"name": "main", "url": "https://ci.example.com/teams-example/job/Example/job/main/"},
+			 "actions": [{"parameters": [{"name": "tenant", "value": "a"}]}]},
+			{"id": 8, "stuck": true, "why": "There are no nodes with the label 'gpu'", "inQueueSince": 1614600001000,
+			 "task": {"name": "other", "url": "https://ci.example.com/teams-example/job/Example/job/other/"}}
+		]}`)
+	}))
+	defer server.Close()
+
+	topology := Topology{Root: server.URL}
+	items, err := GetQueueItems(gojenkins.CreateJenkins(server.Client(), server.URL), topology, []string{"example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("GetQueueItems() = %v", items)
+	}
+	if items[0].ID != 7 || items[0].Job != "Example/main" || !reflect.DeepEqual(items[0].Parameters, map[string]string{"tenant": "a"}) {
+		t.Errorf("GetQueueItems()[0] = %+v", items[0])
+	}
+	if items[1].ID != 8 || !items[1].Stuck || items[1].Why != "There are no nodes with the label 'gpu'" {
+		t.Errorf("GetQueueItems()[1] = %+v", items[1])
+	}
+}
@@ -0,0 +1,219 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	color "github.com/logrusorgru/aurora/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	StageSucceeded = "succeeded"
+	StageFailed    = "failed"
+	StageSkipped   = "skipped"
+)
+
+// Stage is a named group of batch jobs. A stage starts as soon as every stage
+// it depends on has succeeded.
+type Stage struct {
+	Name      string     `yaml:"Name"`
+	DependsOn []string   `yaml:"DependsOn,omitempty"`
+	Batch     []BatchJob `yaml:"BatchJobs"`
+}
+
+// StageResult is the outcome of a single stage.
+type StageResult struct {
+	Name   string
+	Status string
+	Err    error
+	// SkippedBecause holds the upstream stage that failed or was skipped.
+	SkippedBecause string
+}
+
+// ValidateStages makes sure stage names are unique, every dependency exists
+// and the dependencies don't form a cycle.
+func ValidateStages(stages []Stage) error {
+	byName := map[string]Stage{}
+	for _, stage := range stages {
+		if stage.Name == "" {
+			return errors.New("every stage needs a Name")
+		}
+		if _, ok := byName[stage.Name]; ok {
+			return errors.Errorf("stage %s is defined more than once", stage.Name)
+		}
+		byName[stage.Name] = stage
+	}
+	for _, stage := range stages {
+		for _, dep := range stage.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return errors.Errorf("stage %s depends on unknown stage %s", stage.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == name {
+					start = i
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return errors.Errorf("stages form a cycle: %s", strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range byName[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, stage := range stages {
+		if err := visit(stage.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunStages runs every stage of j as soon as its dependencies finish. Stages
+// whose upstream failed are skipped, and with FailFast so is every stage
+// that hasn't started when the first one fails. The results are returned in
+// declaration order.
+func RunStages(j JobList, client Client) ([]StageResult, error) {
+	return runStageGraph(j.out(), j.Stages, j.failFast(), func(stage Stage) error {
+		return RunJobList(JobList{Batch: stage.Batch, LogDir: j.LogDir, RunPolicy: j.RunPolicy, Status: j.Status, Out: j.Out}, client)
+	})
+}
+
+func runStageGraph(out io.Writer, stages []Stage, failFast bool, run func(Stage) error) ([]StageResult, error) {
+	if err := ValidateStages(stages); err != nil {
+		return nil, err
+	}
+
+	done := map[string]chan struct{}{}
+	for _, stage := range stages {
+		done[stage.Name] = make(chan struct{})
+	}
+	results := make([]StageResult, len(stages))
+	byName := map[string]*StageResult{}
+	for i, stage := range stages {
+		results[i].Name = stage.Name
+		byName[stage.Name] = &results[i]
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	firstFailure := ""
+	for i, stage := range stages {
+		wg.Add(1)
+		go func(stage Stage, result *StageResult) {
+			defer wg.Done()
+			defer close(done[stage.Name])
+
+			for _, dep := range stage.DependsOn {
+				<-done[dep]
+				mu.Lock()
+				upstream := byName[dep].Status
+				mu.Unlock()
+				if upstream != StageSucceeded {
+					mu.Lock()
+					result.Status = StageSkipped
+					result.SkippedBecause = dep
+					mu.Unlock()
+					return
+				}
+			}
+
+			mu.Lock()
+			if failFast && firstFailure != "" {
+				result.Status = StageSkipped
+				result.SkippedBecause = firstFailure
+				mu.Unlock()
+				return
+			}
+			mu.Unlock()
+
+			fmt.Fprintln(out, color.Cyan("Starting stage"), color.White(stage.Name))
+			err := run(stage)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Status = StageFailed
+				result.Err = err
+				if firstFailure == "" {
+					firstFailure = stage.Name
+				}
+				return
+			}
+			result.Status = StageSucceeded
+		}(stage, &results[i])
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Status != StageSucceeded {
+			return results, errors.New("one or more stages did not succeed")
+		}
+	}
+	return results, nil
+}
+
+// PrintStageReport writes the status of every stage to out, including why
+// skipped stages never ran.
+func PrintStageReport(out io.Writer, results []StageResult) {
+	fmt.Fprintln(out, "Stage summary:")
+	for _, result := range results {
+		switch result.Status {
+		case StageSucceeded:
+			fmt.Fprintln(out, " ", color.Green("✔"), result.Name)
+		case StageFailed:
+			fmt.Fprintln(out, " ", color.Red("✘"), result.Name, color.Red(result.Err))
+		case StageSkipped:
+			fmt.Fprintln(out, " ", color.Yellow("-"), result.Name, color.Yellow(fmt.Sprintf("skipped because %s did not succeed", result.SkippedBecause)))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestValidateStages(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []Stage
+		wantErr string
+	}{
+		{
+			name: "TestValid",
+			stages: []Stage{
+				{Name: "build"},
+				{Name: "deploy-a", DependsOn: []string{"build"}},
+				{Name: "deploy-b", DependsOn: []string{"build"}},
+				{Name: "verify", DependsOn: []string{"deploy-a", "deploy-b"}},
+			},
+		},
+		{
+			name:    "TestUnknownDependency",
+			stages:  []Stage{{Name: "deploy", DependsOn: []string{"build"}}},
+			wantErr: "stage deploy depends on unknown stage build",
+		},
+		{
+			name:    "TestDuplicate",
+			stages:  []Stage{{Name: "build"}, {Name: "build"}},
+			wantErr: "stage build is defined more than once",
+		},
+		{
+			name: "TestCycle",
+			stages: []Stage{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			wantErr: "stages form a cycle: a -> c -> b -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStages(tt.stages)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidateStages() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("ValidateStages() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunStageGraph(t *testing.T) {
+	stages := []Stage{
+		{Name: "build"},
+		{Name: "deploy-a", DependsOn: []string{"build"}},
+		{Name: "deploy-b", DependsOn: []string{"build"}},
+		{Name: "verify-a", DependsOn: []string{"deploy-a"}},
+		{Name: "verify-all", DependsOn: []string{"verify-a", "deploy-b"}},
+	}
+
+	var mu sync.Mutex
+	var order []string
+	results, err := runStageGraph(ioutil.Discard, stages, false, func(stage Stage) error {
+		mu.Lock()
+		order = append(order, stage.Name)
+		mu.Unlock()
+		if stage.Name == "deploy-a" {
+			return errors.New("build failed")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Errorf("runStageGraph() error = nil, want failure")
+	}
+	if order[0] != "build" {
+		t.Errorf("runStageGraph() ran %s first, want build", order[0])
+	}
+
+	got := map[string]string{}
+	skipped := map[string]string{}
+	for _, result := range results {
+		got[result.Name] = result.Status
+		skipped[result.Name] = result.SkippedBecause
+	}
+	want := map[string]string{
+		"build":      StageSucceeded,
+		"deploy-a":   StageFailed,
+		"deploy-b":   StageSucceeded,
+		"verify-a":   StageSkipped,
+		"verify-all": StageSkipped,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runStageGraph() = %v, want %v", got, want)
+	}
+	if skipped["verify-a"] != "deploy-a" || skipped["verify-all"] != "verify-a" {
+		t.Errorf("runStageGraph() skipped reasons = %v", skipped)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunSuccess   = "success"
+	RunFailure   = "failure"
+	RunCancelled = "cancelled"
+)
+
+// RunStatus is a point in time view of one run of a batch.
+type RunStatus struct {
+	Tag      string
+	Status   string
+	Build    int64
+	URL      string
+	Started  time.Time
+	Finished time.Time
+	LastLine string
+}
+
+// Elapsed is how long the run has been going, or how long it took once it
+// finished.
+func (r RunStatus) Elapsed() time.Duration {
+	switch {
+	case r.Started.IsZero():
+		return 0
+	case r.Finished.IsZero():
+		return time.Since(r.Started)
+	default:
+		return r.Finished.Sub(r.Started)
+	}
+}
+
+// BatchStatus tracks every run of a batch while it executes so it can be
+// shown on a dashboard. It is safe to use from many goroutines.
+type BatchStatus struct {
+	mu   sync.Mutex
+	runs []*RunStatus
+}
+
+// NewBatchStatus creates an empty BatchStatus.
+func NewBatchStatus() *BatchStatus {
+	return &BatchStatus{}
+}
+
+// Add registers a queued run and returns its index.
+func (s *BatchStatus) Add(tag string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs = append(s.runs, &RunStatus{Tag: tag, Status: RunQueued})
+	return len(s.runs) - 1
+}
+
+// Start marks a run as running as the given build.
+func (s *BatchStatus) Start(index int, build int64, url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.runs[index]
+	run.Status = RunRunning
+	run.Build = build
+	run.URL = url
+	run.Started = time.Now()
+}
+
+// Finish records the final status of a run.
+func (s *BatchStatus) Finish(index int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.runs[index]
+	run.Status = status
+	if !run.Started.IsZero() {
+		run.Finished = time.Now()
+	}
+}
+
+// Snapshot returns a copy of every run in the order they were added.
+func (s *BatchStatus) Snapshot() []RunStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := make([]RunStatus, len(s.runs))
+	for i, run := range s.runs {
+		runs[i] = *run
+	}
+	return runs
+}
+
+// Failed reports whether any run didn't succeed.
+func (s *BatchStatus) Failed() bool {
+	for _, run := range s.Snapshot() {
+		if run.Status == RunFailure || run.Status == RunCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer returns a writer that keeps the last non-empty line written to it as
+// the run's LastLine.
+func (s *BatchStatus) Writer(index int) io.Writer {
+	return &lastLineWriter{status: s, index: index}
+}
+
+type lastLineWriter struct {
+	status *BatchStatus
+	index  int
+	buf    bytes.Buffer
+}
+
+func (w *lastLineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(w.buf.Next(i + 1)))
+		if line != "" {
+			w.status.mu.Lock()
+			w.status.runs[w.index].LastLine = line
+			w.status.mu.Unlock()
+		}
+	}
+	return len(p), nil
+}
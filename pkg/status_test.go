@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"testing"
+)
+
+func TestBatchStatus(t *testing.T) {
+	status := NewBatchStatus()
+	a := status.Add("a")
+	b := status.Add("b")
+
+	status.Start(a, 12, "https://ci/job/a/12/")
+	w := status.Writer(a)
+	w.Write([]byte("Cloning...\nDeploying"))
+	w.Write([]byte(" tenant\n\n"))
+	status.Finish(b, RunCancelled)
+
+	runs := status.Snapshot()
+	if runs[a].Status != RunRunning || runs[a].Build != 12 || runs[a].LastLine != "Deploying tenant" {
+		t.Errorf("run a = %+v", runs[a])
+	}
+	if runs[b].Status != RunCancelled || runs[b].Elapsed() != 0 {
+		t.Errorf("run b = %+v", runs[b])
+	}
+	if !status.Failed() {
+		t.Errorf("Failed() = false, want true with a cancelled run")
+	}
+}
@@ -0,0 +1,88 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// The ways of ending a build, gentlest first. Jenkins only offers term and
+// kill for pipelines, once the gentler ones have been tried.
+var stopSteps = []string{"stop", "term", "kill"}
+
+// StopBuild aborts a running build. When it is still running after grace it
+// escalates from stop to term and then to kill.
+func StopBuild(ctx context.Context, B *gojenkins.Build, grace time.Duration, out io.Writer) (*BuildResult, error) {
+	for _, step := range stopSteps {
+		if _, err := B.Poll(ctx); err != nil {
+			return nil, errors.Wrapf(err, "could not get the state of %s", B.GetUrl())
+		}
+		if !B.Raw.Building {
+			return WaitForResult(ctx, B)
+		}
+
+		fmt.Fprintf(out, "Sending %s to build %d...\n", step, B.GetBuildNumber())
+		resp, err := B.Jenkins.Requester.Post(ctx, B.Base+"/"+step, nil, nil, nil)
+		if err != nil {
+			fmt.Fprintf(out, "Could not %s build %d: %v\n", step, B.GetBuildNumber(), err)
+		} else if resp.StatusCode >= 400 {
+			fmt.Fprintf(out, "Could not %s build %d: %s\n", step, B.GetBuildNumber(), resp.Status)
+		}
+
+		if stopped, err := waitStopped(ctx, B, grace); err != nil {
+			return nil, err
+		} else if stopped {
+			return WaitForResult(ctx, B)
+		}
+	}
+	return NewBuildResult(B), errors.Errorf("build %d is still running after %s", B.GetBuildNumber(), stopSteps[len(stopSteps)-1])
+}
+
+// waitStopped polls a build for up to grace and reports whether it ended.
+func waitStopped(ctx context.Context, B *gojenkins.Build, grace time.Duration) (bool, error) {
+	deadline := time.Now().Add(grace)
+	for {
+		if _, err := B.Poll(ctx); err != nil {
+			return false, errors.Wrapf(err, "could not get the state of %s", B.GetUrl())
+		}
+		if !B.Raw.Building {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
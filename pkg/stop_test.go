@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestStopBuild(t *testing.T) {
+	tests := []struct {
+		name      string
+		stopsOn   string
+		wantSteps []string
+		wantErr   bool
+	}{
+		{name: "TestStop", stopsOn: "stop", wantSteps: []string{"stop"}},
+		{name: "TestEscalate", stopsOn: "kill", wantSteps: []string{"stop", "term", "kill"}},
+		{name: "TestStuck", stopsOn: "never", wantSteps: []string{"stop", "term", "kill"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			steps := []string{}
+			building := true
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+				switch {
+				case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/job/Example/1/"):
+					step := strings.TrimPrefix(r.URL.Path, "/job/Example/1/")
+					steps = append(steps, step)
+					if step == tt.stopsOn {
+						building = false
+					}
+				case r.URL.Path == "/job/Example/1/api/json":
+					result := ""
+					if !building {
+						result = "ABORTED"
+					}
+					fmt.Fprintf(w, `{"number": 1, "building": %t, "result": %q}`, building, result)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			jenkins := gojenkins.CreateJenkins(server.Client(), server.URL)
+			build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/Example/1", Raw: &gojenkins.BuildResponse{}}
+
+			result, err := StopBuild(context.Background(), build, 0, ioutil.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StopBuild() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result.Result != "ABORTED" {
+				t.Errorf("StopBuild() result = %s, want ABORTED", result.Result)
+			}
+			if !reflect.DeepEqual(steps, tt.wantSteps) {
+				t.Errorf("StopBuild() sent %v, want %v", steps, tt.wantSteps)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// ErrNoTestReport is returned for builds that didn't publish JUnit results.
+var ErrNoTestReport = errors.New("the build has no test report")
+
+// How far back PreviousBuild looks for a build that still exists.
+const previousBuildLookback = 10
+
+// TestCase is a single test of a JUnit report.
+type TestCase struct {
+	Suite           string  `json:"suite" yaml:"suite"`
+	ClassName       string  `json:"className" yaml:"className"`
+	Name            string  `json:"name" yaml:"name"`
+	Status          string  `json:"status" yaml:"status"`
+	DurationSeconds float64 `json:"durationSeconds" yaml:"durationSeconds"`
+	ErrorDetails    string  `json:"errorDetails,omitempty" yaml:"errorDetails,omitempty"`
+	ErrorStackTrace string  `json:"errorStackTrace,omitempty" yaml:"errorStackTrace,omitempty"`
+	FailedSince     int64   `json:"failedSince,omitempty" yaml:"failedSince,omitempty"`
+}
+
+// FullName identifies a test case across builds.
+func (c TestCase) FullName() string {
+	if c.ClassName == "" {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
+
+// TestReport sums up the JUnit report of a build.
+type TestReport struct {
+	Build           int64      `json:"build" yaml:"build"`
+	Passed          int64      `json:"passed" yaml:"passed"`
+	Failed          int64      `json:"failed" yaml:"failed"`
+	Skipped         int64      `json:"skipped" yaml:"skipped"`
+	DurationSeconds float64    `json:"durationSeconds" yaml:"durationSeconds"`
+	Failures        []TestCase `json:"failures" yaml:"failures"`
+}
+
+// TestComparison tells the failures of a build apart from those of the
+// build before it.
+type TestComparison struct {
+	Previous     int64    `json:"previous" yaml:"previous"`
+	NewFailures  []string `json:"newFailures" yaml:"newFailures"`
+	StillFailing []string `json:"stillFailing" yaml:"stillFailing"`
+	Fixed        []string `json:"fixed" yaml:"fixed"`
+}
+
+// GetTestReport reads the JUnit report of a build.
+func GetTestReport(ctx context.Context, B *gojenkins.Build) (*TestReport, error) {
+	raw := gojenkins.TestResult{}
+	resp, err := B.Jenkins.Requester.GetJSON(ctx, B.Base+"/testReport", &raw, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get the test report of %s", B.GetUrl())
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoTestReport
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not get the test report of %s: %s", B.GetUrl(), resp.Status)
+	}
+
+	report := &TestReport{
+		Build:           B.GetBuildNumber(),
+		Passed:          raw.PassCount,
+		Failed:          raw.FailCount,
+		Skipped:         raw.SkipCount,
+		DurationSeconds: raw.Duration,
+		Failures:        []TestCase{},
+	}
+	for _, suite := range raw.Suites {
+		for _, c := range suite.Cases {
+			if !testFailed(c.Status) {
+				continue
+			}
+			report.Failures = append(report.Failures, TestCase{
+				Suite:           suite.Name,
+				ClassName:       c.ClassName,
+				Name:            c.Name,
+				Status:          c.Status,
+				DurationSeconds: c.Duration,
+				ErrorDetails:    optionalString(c.ErrorDetails),
+				ErrorStackTrace: optionalString(c.ErrorStackTrace),
+				FailedSince:     c.FailedSince,
+			})
+		}
+	}
+	return report, nil
+}
+
+func testFailed(status string) bool {
+	return status == "FAILED" || status == "REGRESSION"
+}
+
+// optionalString reads the fields jenkins sends as null when they're empty.
+func optionalString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// PreviousBuild returns the closest earlier build of the same job that
+// still exists.
+func PreviousBuild(ctx context.Context, B *gojenkins.Build) (*gojenkins.Build, error) {
+	if B.Job == nil {
+		return nil, errors.New("the build doesn't know its job")
+	}
+	number := B.GetBuildNumber()
+	for n := number - 1; n > 0 && n >= number-previousBuildLookback; n-- {
+		if previous, err := B.Job.GetBuild(ctx, n); err == nil && previous != nil {
+			return previous, nil
+		}
+	}
+	return nil, errors.Errorf("build %d has no previous build", number)
+}
+
+// CompareTestReports sorts the failures of current into new ones and ones
+// that already failed in previous, and lists what previous failed on that
+// passes now.
+func CompareTestReports(current *TestReport, previous *TestReport) TestComparison {
+	comparison := TestComparison{
+		Previous:     previous.Build,
+		NewFailures:  []string{},
+		StillFailing: []string{},
+		Fixed:        []string{},
+	}
+	failedBefore := map[string]bool{}
+	for _, c := range previous.Failures {
+		failedBefore[c.FullName()] = true
+	}
+	failedNow := map[string]bool{}
+	for _, c := range current.Failures {
+		failedNow[c.FullName()] = true
+		if failedBefore[c.FullName()] {
+			comparison.StillFailing = append(comparison.StillFailing, c.FullName())
+		} else {
+			comparison.NewFailures = append(comparison.NewFailures, c.FullName())
+		}
+	}
+	for name := range failedBefore {
+		if !failedNow[name] {
+			comparison.Fixed = append(comparison.Fixed, name)
+		}
+	}
+	sort.Strings(comparison.NewFailures)
+	sort.Strings(comparison.StillFailing)
+	sort.Strings(comparison.Fixed)
+	return comparison
+}
@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kireledan/gojenkins"
+)
+
+const testReport = `{"duration": 12.5, "failCount": 2, "passCount": 40, "skipCount": 1, "suites": [
+	{"name": "unit", "cases": [
+		{"className": "com.example.ParserTest", "name": "parsesEmpty", "status": "PASSED"},
+		{"className": "com.example.ParserTest", "name": "parsesNested", "status": "REGRESSION", "errorDetails": "expected 2 but was 3", "errorStackTrace": "at ParserTest.java:42", "failedSince": 7},
+		{"className": "com.example.ClientTest", "name": "retries", "status": "FAILED", "errorDetails": null, "errorStackTrace": null, "failedSince": 3},
+		{"className": "com.example.ClientTest", "name": "times out", "status": "SKIPPED"}
+	]}
+]}`
+
+func TestGetTestReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case "/job/Example/7/testReport/api/json":
+			fmt.Fprint(w, testReport)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	jenkins := gojenkins.CreateJenkins(server.Client(), server.URL)
+	build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/Example/7", Raw: &gojenkins.BuildResponse{Number: 7}}
+
+	report, err := GetTestReport(context.Background(), build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Build != 7 || report.Passed != 40 || report.Failed != 2 || report.Skipped != 1 || report.DurationSeconds != 12.5 {
+		t.Errorf("GetTestReport() = %+v", report)
+	}
+	want := []TestCase{
+		{Suite: "unit", ClassName: "com.example.ParserTest", Name: "parsesNested", Status: "REGRESSION", ErrorDetails: "expected 2 but was 3", ErrorStackTrace: "at ParserTest.java:42", FailedSince: 7},
+		{Suite: "unit", ClassName: "com.example.ClientTest", Name: "retries", Status: "FAILED", FailedSince: 3},
+	}
+	if !reflect.DeepEqual(report.Failures, want) {
+		t.Errorf("GetTestReport() failures = %+v, want %+v", report.Failures, want)
+	}
+
+	missing := &gojenkins.Build{Jenkins: jenkins, Base: "/job/Example/6", Raw: &gojenkins.BuildResponse{Number: 6}}
+	if _, err := GetTestReport(context.Background(), missing); err != ErrNoTestReport {
+		t.Errorf("GetTestReport() of a build without tests = %v, want %v", err, ErrNoTestReport)
+	}
+}
+
+func TestCompareTestReports(t *testing.T) {
+	failures := func(names ...string) []TestCase {
+		cases := []TestCase{}
+		for _, name := range names {
+			cases = append(cases, TestCase{ClassName: "Suite", Name: name})
+		}
+		return cases
+	}
+	tests := []struct {
+		name     string
+		current  []TestCase
+		previous []TestCase
+		want     TestComparison
+	}{
+		{
+			name:    "nothing failing",
+			current: failures(), previous: failures(),
+			want: TestComparison{Previous: 6, NewFailures: []string{}, StillFailing: []string{}, Fixed: []string{}},
+		},
+		{
+			name:    "new and old failures",
+			current: failures("b", "a", "c"), previous: failures("c", "d"),
+			want: TestComparison{Previous: 6, NewFailures: []string{"Suite.a", "Suite.b"}, StillFailing: []string{"Suite.c"}, Fixed: []string{"Suite.d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareTestReports(&TestReport{Build: 7, Failures: tt.current}, &TestReport{Build: 6, Failures: tt.previous})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareTestReports() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
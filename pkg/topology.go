@@ -0,0 +1,237 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// The layouts a Topology can have.
+const (
+	// TopologyTeams is the CloudBees layout: the first folder of a path is a
+	// team, whose controller is at <root>/teams-<team>.
+	TopologyTeams = "teams"
+	// TopologyFlat is a single jenkins at the root url.
+	TopologyFlat = "flat"
+	// TopologyFolders sends the jobs under each folder prefix of Controllers
+	// to its controller, and every other job to the root url.
+	TopologyFolders = "folders"
+)
+
+// Topology tells which controller the job at a path lives on. Paths are the
+// full names of jobs on their controller, e.g. Example/Folder/main, and the
+// controller a path goes to is called its team. It is read from the
+// topology key of ~/.goose.yaml:
+//
+//	topology:
+//	  kind: folders
+//	  controllers:
+//	    payments: https://ci.example.com/payments
+//	    infra/tools: https://tools.example.com
+type Topology struct {
+	// Kind is TopologyTeams, TopologyFlat or TopologyFolders. Empty means
+	// TopologyTeams.
+	Kind string `mapstructure:"kind" json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Root is the url of jenkins, $JENKINS_ROOT_URL by default.
+	Root string `mapstructure:"root" json:"root,omitempty" yaml:"root,omitempty"`
+	// Login lists the teams in the teams layout, $JENKINS_LOGIN_URL by
+	// default.
+	Login string `mapstructure:"login" json:"login,omitempty" yaml:"login,omitempty"`
+	// Controllers maps folder prefixes to controller urls in the folders
+	// layout. The folders are looked up on their controller by their full
+	// path.
+	Controllers map[string]string `mapstructure:"controllers" json:"controllers,omitempty" yaml:"controllers,omitempty"`
+}
+
+// Validate checks that the topology names a known layout and has the urls
+// it needs.
+func (t Topology) Validate() error {
+	switch t.kind() {
+	case TopologyTeams:
+		if t.Login == "" {
+			return errors.New("the teams topology needs a login url")
+		}
+	case TopologyFlat, TopologyFolders:
+	default:
+		return errors.Errorf("unknown topology %q, use %s, %s or %s", t.Kind, TopologyTeams, TopologyFlat, TopologyFolders)
+	}
+	if t.Root == "" {
+		return errors.New("the topology needs a root url")
+	}
+	for prefix, controller := range t.Controllers {
+		if _, err := url.Parse(controller); err != nil || controller == "" {
+			return errors.Errorf("the controller of %s is not a url: %q", prefix, controller)
+		}
+	}
+	return nil
+}
+
+func (t Topology) kind() string {
+	if t.Kind == "" {
+		return TopologyTeams
+	}
+	return t.Kind
+}
+
+// LoginURL is where goose logs in: the login url in the teams layout, the
+// root url otherwise.
+func (t Topology) LoginURL() string {
+	if t.kind() == TopologyTeams {
+		return t.Login
+	}
+	return t.Root
+}
+
+// Team is the team of the job at path: its first folder in the teams
+// layout, its longest prefix in Controllers in the folders layout, and ""
+// for the root url.
+func (t Topology) Team(path string) string {
+	switch t.kind() {
+	case TopologyTeams:
+		return strings.SplitN(path, "/", 2)[0]
+	case TopologyFolders:
+		team := ""
+		for prefix := range t.Controllers {
+			prefix = strings.Trim(prefix, "/")
+			if hasPathPrefix(path, prefix) && len(prefix) > len(team) {
+				team = prefix
+			}
+		}
+		return team
+	}
+	return ""
+}
+
+// ControllerURL is the url of a team's controller.
+func (t Topology) ControllerURL(team string) string {
+	root := strings.TrimSuffix(t.Root, "/")
+	switch t.kind() {
+	case TopologyTeams:
+		return root + "/teams-" + team
+	case TopologyFolders:
+		for prefix, controller := range t.Controllers {
+			if strings.Trim(prefix, "/") == team && team != "" {
+				return strings.TrimSuffix(controller, "/")
+			}
+		}
+	}
+	return root
+}
+
+// View is a client for the controller of team, logged in like J. J itself
+// is left pointing where it was: every team gets a view of its own, so
+// requests to different teams can run side by side.
+func (t Topology) View(J *gojenkins.Jenkins, team string) *gojenkins.Jenkins {
+	return viewAt(J, t.ControllerURL(team))
+}
+
+// FixedTeams lists the teams of the flat and folders layouts. The jenkins
+// at the root url is the team "", in the folders layout it holds the jobs
+// outside of every prefix. The teams of the teams layout are listed by their
+// login url instead.
+func (t Topology) FixedTeams() []string {
+	teams := []string{}
+	for prefix := range t.Controllers {
+		if t.kind() == TopologyFolders {
+			teams = append(teams, strings.Trim(prefix, "/"))
+		}
+	}
+	sort.Strings(teams)
+	return append([]string{""}, teams...)
+}
+
+// JobPath turns the url of a job, or the path of that url, into the job's
+// path. What follows the job in the url, such as a build number, is
+// returned as rest:
+// https://ci/teams-team/job/Folder/job/main/12/ is Folder/main and [12] in
+// the teams layout.
+func (t Topology) JobPath(jobURL string) (string, []string) {
+	u, err := url.Parse(jobURL)
+	if err != nil {
+		return jobURL, nil
+	}
+	// Keep branch names such as feature%2Fthing escaped, like GetNestedJob expects
+	path := trimPathPrefix(strings.Trim(u.EscapedPath(), "/"), t.controllerPath(u))
+	segments := strings.Split(path, "/")
+	parts := []string{}
+	for len(segments) >= 2 && segments[0] == "job" {
+		parts = append(parts, segments[1])
+		segments = segments[2:]
+	}
+	if len(segments) == 1 && segments[0] == "" {
+		segments = nil
+	}
+	return strings.Join(parts, "/"), segments
+}
+
+// controllerPath is the path of the controller u belongs to.
+func (t Topology) controllerPath(u *url.URL) string {
+	path := strings.Trim(u.EscapedPath(), "/")
+	root := urlPath(t.Root)
+	switch t.kind() {
+	case TopologyTeams:
+		team := strings.SplitN(trimPathPrefix(path, root), "/", 2)[0]
+		if strings.HasPrefix(team, "teams-") {
+			return strings.Trim(root+"/"+team, "/")
+		}
+	case TopologyFolders:
+		longest := ""
+		for _, controller := range t.Controllers {
+			if p := urlPath(controller); hasPathPrefix(path, p) && len(p) > len(longest) {
+				longest = p
+			}
+		}
+		if longest != "" {
+			return longest
+		}
+	}
+	return root
+}
+
+// urlPath is the path of a url without its slashes.
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(u.EscapedPath(), "/")
+}
+
+// hasPathPrefix reports whether path is prefix or inside it.
+func hasPathPrefix(path string, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func trimPathPrefix(path string, prefix string) string {
+	if !hasPathPrefix(path, prefix) {
+		return path
+	}
+	return strings.Trim(strings.TrimPrefix(path, prefix), "/")
+}
@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/rallyhealth/goose/pkg/fakejenkins"
+)
+
+var testTopologies = map[string]Topology{
+	TopologyTeams: {Root: "https://ci.example.com", Login: "https://ci.example.com/cjoc"},
+	TopologyFlat:  {Kind: TopologyFlat, Root: "https://ci.example.com/jenkins/"},
+	TopologyFolders: {Kind: TopologyFolders, Root: "https://ci.example.com", Controllers: map[string]string{
+		"payments":    "https://ci.example.com/payments/",
+		"infra/tools": "https://tools.example.com",
+	}},
+}
+
+func TestTopologyTeam(t *testing.T) {
+	tests := []struct {
+		name           string
+		topology       string
+		path           string
+		wantTeam       string
+		wantController string
+	}{
+		{name: "TestTeams", topology: TopologyTeams, path: "example/app/master", wantTeam: "example", wantController: "https://ci.example.com/teams-example"},
+		{name: "TestFlat", topology: TopologyFlat, path: "example/app/master", wantTeam: "", wantController: "https://ci.example.com/jenkins"},
+		{name: "TestFolder", topology: TopologyFolders, path: "payments/api/main", wantTeam: "payments", wantController: "https://ci.example.com/payments"},
+		{name: "TestNestedFolder", topology: TopologyFolders, path: "infra/tools/deploy", wantTeam: "infra/tools", wantController: "https://tools.example.com"},
+		{name: "TestFolderPrefixOnly", topology: TopologyFolders, path: "paymentsv2/api", wantTeam: "", wantController: "https://ci.example.com"},
+		{name: "TestUnmappedFolder", topology: TopologyFolders, path: "infra/other", wantTeam: "", wantController: "https://ci.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology := testTopologies[tt.topology]
+			team := topology.Team(tt.path)
+			if team != tt.wantTeam {
+				t.Errorf("Team() = %q, want %q", team, tt.wantTeam)
+			}
+			if got := topology.ControllerURL(team); got != tt.wantController {
+				t.Errorf("ControllerURL() = %s, want %s", got, tt.wantController)
+			}
+		})
+	}
+}
+
+func TestTopologyParseBuildPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology string
+		url      string
+		want     Build
+	}{
+		{name: "TestTeams", topology: TopologyTeams, url: "https://ci.example.com/teams-example/job/example/job/app/job/master/12/", want: Build{Job: "example/app/master", BuildNumber: 12}},
+		{name: "TestFlat", topology: TopologyFlat, url: "https://ci.example.com/jenkins/job/example/job/feature%2Fx/3", want: Build{Job: "example/feature%2Fx", BuildNumber: 3}},
+		{name: "TestFolder", topology: TopologyFolders, url: "https://ci.example.com/payments/job/payments/job/api/job/main/7/", want: Build{Job: "payments/api/main", BuildNumber: 7}},
+		{name: "TestOtherHost", topology: TopologyFolders, url: "https://tools.example.com/job/infra/job/tools/job/deploy/1/", want: Build{Job: "infra/tools/deploy", BuildNumber: 1}},
+		{name: "TestJobOnly", topology: TopologyFlat, url: "https://ci.example.com/jenkins/job/example/", want: Build{Job: "example"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testTopologies[tt.topology].ParseBuildPath(tt.url); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBuildPath() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology Topology
+		wantErr  bool
+	}{
+		{name: "TestTeams", topology: testTopologies[TopologyTeams]},
+		{name: "TestFlat", topology: testTopologies[TopologyFlat]},
+		{name: "TestFolders", topology: testTopologies[TopologyFolders]},
+		{name: "TestTeamsWithoutLogin", topology: Topology{Root: "https://ci.example.com"}, wantErr: true},
+		{name: "TestWithoutRoot", topology: Topology{Kind: TopologyFlat}, wantErr: true},
+		{name: "TestUnknownKind", topology: Topology{Kind: "mesh", Root: "https://ci.example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.topology.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTopologies looks up, lists, indexes and queues jobs at the root of a
+// fake jenkins as a flat jenkins, and on a team controller mapped by folder
+// next to the root.
+func TestTopologies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goose-topology")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldIndex := jobIndexPath
+	defer func() { jobIndexPath = oldIndex }()
+
+	server := fakejenkins.New(
+		&fakejenkins.Team{Name: "", Jobs: []*fakejenkins.Job{{Name: "example", Jobs: []*fakejenkins.Job{
+			{Name: "app", Jobs: []*fakejenkins.Job{{Name: "master", Repo: "https://github.com/example/app.git"}}},
+		}}}, Queue: []*fakejenkins.QueueItem{{ID: 7, Job: "example/app/master", Since: time.Now()}}},
+		&fakejenkins.Team{Name: "payments", Jobs: []*fakejenkins.Job{{Name: "payments", Jobs: []*fakejenkins.Job{
+			{Name: "api", Jobs: []*fakejenkins.Job{{Name: "main", Repo: "https://github.com/example/payments.git"}}},
+		}}}},
+	)
+	defer server.Close()
+
+	topologies := []struct {
+		name     string
+		topology Topology
+		teams    []string
+		top      []string
+		paths    []string
+		indexed  map[string][]string
+	}{
+		{name: "TestFlat", topology: Topology{Kind: TopologyFlat, Root: server.URL}, teams: []string{""}, top: []string{"example"},
+			paths: []string{"example/app/master"}, indexed: map[string][]string{"github.com/example/app.git": {"example/app"}}},
+		{name: "TestFolders", topology: Topology{Kind: TopologyFolders, Root: server.URL, Controllers: map[string]string{"payments": server.URL + "/teams-payments"}},
+			teams: []string{"", "payments"}, top: []string{"payments", "example"}, paths: []string{"payments/api/main", "example/app/master"},
+			indexed: map[string][]string{"github.com/example/payments.git": {"payments/api"}, "github.com/example/app.git": {"example/app"}}},
+	}
+	for i, tt := range topologies {
+		t.Run(tt.name, func(t *testing.T) {
+			jobIndexPath = filepath.Join(dir, fmt.Sprint(i))
+			J, err := gojenkins.CreateJenkins(nil, tt.topology.LoginURL(), fakejenkins.User, fakejenkins.Token).Init(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := NewJenkinsClient(J, tt.topology)
+
+			teams, err := client.Teams(context.Background())
+			if err != nil || !reflect.DeepEqual(teams, tt.teams) {
+				t.Errorf("Teams() = %q, %v, want %q", teams, err, tt.teams)
+			}
+			top, err := client.ListJobs(context.Background(), "")
+			names := []string{}
+			for _, job := range top {
+				names = append(names, job.Name)
+			}
+			if err != nil || !reflect.DeepEqual(names, tt.top) {
+				t.Errorf("ListJobs() = %q, %v, want %q", names, err, tt.top)
+			}
+			for _, path := range tt.paths {
+				job, err := client.GetJob(context.Background(), path)
+				if err != nil || job.Raw.FullName != path {
+					t.Fatalf("GetJob() = %v, %v", job, err)
+				}
+				if got, _ := tt.topology.JobPath(job.Raw.URL); got != path {
+					t.Errorf("JobPath(%s) = %s, want %s", job.Raw.URL, got, path)
+				}
+			}
+
+			BuildJobIndex(client)
+			for repo, want := range tt.indexed {
+				if got := GetAffiliatedJobs(repo); !reflect.DeepEqual(got, want) {
+					t.Errorf("GetAffiliatedJobs(%s) = %v, want %v", repo, got, want)
+				}
+			}
+
+			queued := []int64{}
+			for _, team := range teams {
+				items, err := client.GetQueue(context.Background(), team)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, item := range items {
+					queued = append(queued, item.ID)
+				}
+			}
+			if !reflect.DeepEqual(queued, []int64{7}) {
+				t.Errorf("queued items = %v, want [7]", queued)
+			}
+		})
+	}
+}
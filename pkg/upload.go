@@ -0,0 +1,165 @@
+/*
+MIT License
+
+Copyright (c) 2021 Rally Health, Inc.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kireledan/gojenkins"
+	"github.com/pkg/errors"
+)
+
+// SplitFiles separates the values of file parameters, which are paths of
+// local files to upload, from the other parameters.
+func SplitFiles(params []gojenkins.ParameterDefinition, choices map[string]string) (map[string]string, map[string]string) {
+	values := map[string]string{}
+	files := map[string]string{}
+	for name, value := range choices {
+		if param, ok := FindParameter(params, name); ok && param.Type == FileParameter {
+			if value != "" {
+				files[name] = value
+			}
+			continue
+		}
+		values[name] = value
+	}
+	return values, files
+}
+
+// invokeBuild triggers a build of thejob and returns its queue id, or 0 if
+// the job is already queued. File parameters are uploaded from the paths
+// given in choices.
+func invokeBuild(ctx context.Context, thejob *gojenkins.Job, choices map[string]string) (int64, error) {
+	params, err := thejob.GetParameters(ctx)
+	if err != nil {
+		return 0, err
+	}
+	values, files := SplitFiles(params, choices)
+	if len(files) == 0 {
+		return thejob.InvokeSimple(ctx, values)
+	}
+
+	isQueued, err := thejob.IsQueued(ctx)
+	if err != nil || isQueued {
+		return 0, err
+	}
+	body, contentType, err := multipartBody(values, files)
+	if err != nil {
+		return 0, err
+	}
+
+	requester := thejob.Jenkins.Requester
+	endpoint := thejob.Base + "/buildWithParameters"
+	// SetCrumb only fills in the headers of the request it is given
+	crumb := gojenkins.NewAPIRequest("POST", endpoint, nil)
+	requester.SetCrumb(ctx, crumb)
+
+	req, err := http.NewRequest("POST", requester.Base+endpoint, body)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	for key, values := range crumb.Headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	if requester.BasicAuth != nil {
+		req.SetBasicAuth(requester.BasicAuth.Username, requester.BasicAuth.Password)
+	}
+
+	resp, err := transferClient(requester).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return 0, errors.Errorf("could not invoke job %s: %s", thejob.GetName(), resp.Status)
+	}
+	return queueIDFromLocation(resp.Header.Get("Location"))
+}
+
+// transferClient is the requester's client without its overall timeout, for
+// uploads and downloads that take longer than an api call. It keeps the
+// transport, and the request's context bounds how long they may take.
+func transferClient(requester *gojenkins.Requester) *http.Client {
+	if requester.Client == nil {
+		return &http.Client{}
+	}
+	client := *requester.Client
+	client.Timeout = 0
+	return &client
+}
+
+// multipartBody encodes values as form fields and files as file parts named
+// after their parameter, the way jenkins expects file parameters.
+func multipartBody(values map[string]string, files map[string]string) (io.Reader, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range values {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+	for name, filename := range files {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "could not upload %s", name)
+		}
+		part, err := writer.CreateFormFile(name, filepath.Base(filename))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		file.Close()
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "could not upload %s", name)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body, writer.FormDataContentType(), nil
+}
+
+// queueIDFromLocation reads the queue id from the Location header jenkins
+// answers a build request with, e.g. https://ci/queue/item/42/
+func queueIDFromLocation(location string) (int64, error) {
+	if location == "" {
+		return 0, errors.New("jenkins did not say where the build was queued")
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(path.Base(u.Path), 10, 64)
+}
@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kireledan/gojenkins"
+)
+
+func TestMultipartBody(t *testing.T) {
+	file, err := ioutil.TempFile("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("tenants: [a, b]")
+	file.Close()
+
+	params := []gojenkins.ParameterDefinition{
+		{Name: "tenant", Type: ChoiceParameter},
+		{Name: "manifest", Type: FileParameter},
+	}
+	values, files := SplitFiles(params, map[string]string{"tenant": "a", "manifest": file.Name()})
+	if !reflect.DeepEqual(values, map[string]string{"tenant": "a"}) || !reflect.DeepEqual(files, map[string]string{"manifest": file.Name()}) {
+		t.Fatalf("SplitFiles() = %v, %v", values, files)
+	}
+
+	body, contentType, err := multipartBody(values, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, mediaParams, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form, err := multipart.NewReader(body, mediaParams["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := form.Value["tenant"]; !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("tenant = %v, want [a]", got)
+	}
+	if len(form.File["manifest"]) != 1 {
+		t.Fatalf("manifest was not uploaded: %v", form.File)
+	}
+	part, _ := form.File["manifest"][0].Open()
+	content, _ := ioutil.ReadAll(part)
+	if string(content) != "tenants: [a, b]" {
+		t.Errorf("manifest = %q", content)
+	}
+}
+
+func TestQueueIDFromLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		want     int64
+		wantErr  bool
+	}{
+		{name: "TestQueueItem", location: "https://ci.example.com/queue/item/42/", want: 42},
+		{name: "TestMissing", location: "", wantErr: true},
+		{name: "TestNotQueue", location: "https://ci.example.com/job/Example/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queueIDFromLocation(tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("queueIDFromLocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("queueIDFromLocation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferClient(t *testing.T) {
+	transport := &http.Transport{}
+	requester := &gojenkins.Requester{Client: &http.Client{Timeout: 3 * time.Second, Transport: transport}}
+	client := transferClient(requester)
+	if client.Timeout != 0 || client.Transport != transport {
+		t.Errorf("transferClient() = %+v, want the transport without a timeout", client)
+	}
+	if requester.Client.Timeout != 3*time.Second {
+		t.Errorf("transferClient() changed the requester's timeout to %v", requester.Client.Timeout)
+	}
+}
@@ -24,6 +24,7 @@ SOFTWARE.
 package pkg
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -44,7 +45,7 @@ func getJenkinsJob(args []string) []string {
 	}
 }
 
-func AutoLocateJob(args []string, branch string, Jenky *gojenkins.Jenkins) (*gojenkins.Job, string) {
+func AutoLocateJob(args []string, branch string, client Client) (*gojenkins.Job, string) {
 	if len(args) < 1 {
 		jobList := getJenkinsJob(args)
 		chosenJob := ""
@@ -77,14 +78,14 @@ func AutoLocateJob(args []string, branch string, Jenky *gojenkins.Jenkins) (*goj
 		}
 
 		jobPlusBranch := fmt.Sprintf("%s/%s", chosenJob, chosenBranch)
-		thejob, _ := GetNestedJob(Jenky, jobPlusBranch)
+		thejob, _ := client.GetJob(context.TODO(), jobPlusBranch)
 
 		if thejob == nil {
 			fmt.Println(color.Yellow("No job found for"), color.White(chosenBranch))
 			fmt.Println(color.White("Locating PR branch..."))
 			chosenBranch = fmt.Sprintf("PR-%s", GetCurrentPROfBranch())
 			jobPlusBranch = fmt.Sprintf("%s/%s", chosenJob, chosenBranch)
-			thejob, _ = GetNestedJob(Jenky, jobPlusBranch)
+			thejob, _ = client.GetJob(context.TODO(), jobPlusBranch)
 			if thejob != nil {
 				fmt.Println(color.Green("Found job found for"), color.White(chosenBranch))
 			}
@@ -93,7 +94,7 @@ func AutoLocateJob(args []string, branch string, Jenky *gojenkins.Jenkins) (*goj
 		}
 		return thejob, jobPlusBranch
 	} else {
-		thejob, _ := GetNestedJob(Jenky, args[0])
+		thejob, _ := client.GetJob(context.TODO(), args[0])
 		return thejob, args[0]
 	}
 }
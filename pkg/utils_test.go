@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// inGitRepo creates a repo with one commit on master whose origin is
+// remote, and changes into it. Call the returned func when done.
+func inGitRepo(t *testing.T, remote string) func() {
+	dir, err := ioutil.TempDir("", "goose-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := repo.Worktree()
+	worktree.Add("README.md")
+	signature := &object.Signature{Name: "goose", Email: "goose@example.com", When: time.Now()}
+	if _, err := worktree.Commit("Initial commit", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAutoLocateJob(t *testing.T) {
+	_, client, stop := startFakeJenkins(t)
+	defer stop()
+
+	job, path := AutoLocateJob([]string{"example/app/feature//login"}, "", client)
+	if job == nil || job.GetName() != "feature%2Flogin" || path != "example/app/feature//login" {
+		t.Errorf("AutoLocateJob() with a path = %v, %s", job, path)
+	}
+
+	BuildJobIndex(client)
+	defer inGitRepo(t, "git@github.com:example/app.git")()
+	job, path = AutoLocateJob(nil, "", client)
+	if job == nil || job.GetName() != "master" || path != "example/app/master" {
+		t.Errorf("AutoLocateJob() from the repo = %v, %s", job, path)
+	}
+}